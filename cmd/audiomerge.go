@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/nicoxiang/geektime-downloader/internal/audio"
+	"github.com/spf13/cobra"
+)
+
+var audioMergeCmd = &cobra.Command{
+	Use:   "audio-merge <课程音频目录>",
+	Short: "将已下载的课程音频按文章顺序合并为一个带章节和封面的 .m4b 文件(MP4 容器中的 MP3 音频, Apple Books 和 iTunes 无法导入)",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, err := filepath.Abs(args[0])
+		checkError(err)
+		out, err := audio.MergeCourseAudio(cmd.Context(), dir, "", "")
+		checkError(err)
+		fmt.Printf("有声书已生成: %s\n", out)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(audioMergeCmd)
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/nicoxiang/geektime-downloader/internal/search"
+	"github.com/spf13/cobra"
+)
+
+var indexRebuild bool
+
+var indexCmd = &cobra.Command{
+	Use:   "index [下载目录]",
+	Short: "为已下载的 Markdown 文章和评论建立全文搜索索引, 只处理新增和修改的文章",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := downloadFolder
+		if len(args) == 1 {
+			dir = args[0]
+		}
+		dir, err := filepath.Abs(dir)
+		checkError(err)
+		stats, err := search.Update(dir, indexRebuild)
+		checkError(err)
+		fmt.Printf("索引已更新: 新增 %d 篇, 更新 %d 篇, 删除 %d 篇, 共 %d 篇\n", stats.Added, stats.Updated, stats.Removed, stats.Total)
+	},
+}
+
+func init() {
+	indexCmd.Flags().BoolVar(&indexRebuild, "rebuild", false, "丢弃已有索引并重新建立")
+	rootCmd.AddCommand(indexCmd)
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/nicoxiang/geektime-downloader/internal/markdown"
+	"github.com/nicoxiang/geektime-downloader/internal/podcast"
+	"github.com/spf13/cobra"
+)
+
+var (
+	podcastBaseURL string
+	podcastMDDir   string
+	podcastFlavor  string
+	podcastAddr    string
+	podcastServe   bool
+)
+
+var podcastCmd = &cobra.Command{
+	Use:   "podcast <课程音频目录>",
+	Short: "为已下载的课程音频生成播客 RSS 订阅, 并可在局域网内提供订阅服务",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, err := filepath.Abs(args[0])
+		checkError(err)
+
+		mdDir := podcastMDDir
+		if mdDir == "" {
+			// <download folder>/audio/<course> -> <download folder>/markdown/<course>
+			mdDir = filepath.Join(filepath.Dir(filepath.Dir(dir)), "markdown", filepath.Base(dir))
+		}
+
+		flavor, err := markdown.ParseFlavor(podcastFlavor)
+		checkError(err)
+
+		baseURL := podcastBaseURL
+		if baseURL == "" {
+			baseURL, err = podcast.BaseURL(podcastAddr)
+			checkError(err)
+		}
+
+		feed, err := podcast.WriteFeed(dir, mdDir, flavor, baseURL)
+		checkError(err)
+		fmt.Printf("播客订阅已生成: %s\n", feed)
+
+		if !podcastServe {
+			return
+		}
+		fmt.Printf("播客服务已启动, 订阅地址: %s%s, 按 Ctrl+C 退出\n", baseURL, podcast.FeedFileName)
+		checkError(podcast.Serve(cmd.Context(), dir, podcastAddr))
+	},
+}
+
+func init() {
+	podcastCmd.Flags().StringVar(&podcastBaseURL, "base-url", "", "订阅中音频文件的访问地址前缀, 默认使用局域网 IP 和监听端口")
+	podcastCmd.Flags().StringVar(&podcastMDDir, "markdown", "", "课程 Markdown 目录, 音频没有文章摘要时用于生成节目简介, 默认为下载目录下 markdown/<课程名>")
+	podcastCmd.Flags().StringVar(&podcastFlavor, "md-flavor", string(markdown.FlavorDefault), "下载 Markdown 时使用的格式(default, obsidian, hugo, mkdocs, docusaurus), 用于找到节目简介所在的文件")
+	podcastCmd.Flags().StringVar(&podcastAddr, "addr", ":8090", "播客服务监听地址")
+	podcastCmd.Flags().BoolVar(&podcastServe, "serve", false, "生成订阅后启动 HTTP 服务")
+	rootCmd.AddCommand(podcastCmd)
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
@@ -17,12 +18,21 @@ import (
 
 	"github.com/briandowns/spinner"
 	"github.com/manifoldco/promptui"
+	"github.com/nicoxiang/geektime-downloader/internal/audio"
 	"github.com/nicoxiang/geektime-downloader/internal/config"
+	"github.com/nicoxiang/geektime-downloader/internal/epub"
 	"github.com/nicoxiang/geektime-downloader/internal/geektime"
+	"github.com/nicoxiang/geektime-downloader/internal/geektime/response"
+	"github.com/nicoxiang/geektime-downloader/internal/links"
 	"github.com/nicoxiang/geektime-downloader/internal/markdown"
+	"github.com/nicoxiang/geektime-downloader/internal/naming"
 	"github.com/nicoxiang/geektime-downloader/internal/pdf"
 	"github.com/nicoxiang/geektime-downloader/internal/pkg/filenamify"
 	"github.com/nicoxiang/geektime-downloader/internal/pkg/files"
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/images"
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/logger"
+	"github.com/nicoxiang/geektime-downloader/internal/search"
+	"github.com/nicoxiang/geektime-downloader/internal/site"
 	"github.com/nicoxiang/geektime-downloader/internal/video"
 	"github.com/spf13/cobra"
 	"golang.org/x/net/html"
@@ -38,14 +48,39 @@ var (
 	selectedProduct        geektime.Course
 	quality                string
 	downloadComments       bool
+	mdFrontMatter          bool
+	mdFlavorName           string
+	mdFlavor               markdown.Flavor
+	pathTemplateText       string
+	pathTemplate           naming.Template
+	linkResolver           *links.Resolver
 	selectedProductType    productTypeSelectOption
 	columnOutputType       int
 	printPDFWaitSeconds    int
 	printPDFTimeoutSeconds int
+	pdfEngine              string
+	pdfFontPath            string
+	nativePDFWarned        bool
+	pdfMerge               bool
+	audioMerge             bool
+	pdfPaper               string
+	pdfMargin              string
+	pdfLandscape           bool
+	pdfScale               float64
+	pdfHeader              string
+	pdfFooter              string
+	pdfBackground          bool
+	pdfTheme               string
+	pdfDevice              string
+	pdfLayout              pdf.PageLayout
+	imageMaxWidth          int
+	imageQuality           int
+	imageWebPToPNG         bool
 	interval               int
 	productTypeOptions     []productTypeSelectOption
 	geektimeClient         *geektime.Client
 	isEnterprise           bool
+	university             bool
 	waitRand               = rand.New(rand.NewSource(time.Now().UnixNano()))
 	lastError              string
 )
@@ -65,7 +100,6 @@ type articleOpsOption struct {
 
 func init() {
 	downloadFolder = config.DefaultDownloadPath
-	columnOutputType = 3 // 1(pdf) + 2(markdown) = 3
 	concurrency = int(math.Ceil(float64(runtime.NumCPU()) / 2.0))
 	sp = spinner.New(spinner.CharSets[4], 100*time.Millisecond)
 
@@ -73,11 +107,37 @@ func init() {
 	interval = 5                 // 每篇文章下载后等待5秒
 	printPDFWaitSeconds = 15     // PDF 生成等待时间
 	printPDFTimeoutSeconds = 120 // PDF 生成超时时间
+
+	rootCmd.Flags().BoolVar(&isEnterprise, "enterprise", false, "是否下载企业版极客时间资源, 配置中的课程 ID 为企业版课程 ID")
+	rootCmd.Flags().BoolVar(&university, "university", false, "是否下载训练营, 配置中的课程 ID 为训练营课程 ID")
+	rootCmd.Flags().IntVar(&columnOutputType, "output", 3, "专栏的输出内容(1pdf,2markdown,4audio,8epub,16html)可自由组合, 默认 3 即 PDF 和 Markdown")
+	rootCmd.Flags().BoolVar(&mdFrontMatter, "md-front-matter", false, "在 Markdown 开头写入 YAML front matter(文章和课程 ID、标题、章节、作者、发布时间、原文链接、音频路径和标签)")
+	rootCmd.Flags().StringVar(&mdFlavorName, "md-flavor", string(markdown.FlavorDefault), "Markdown 格式(default, obsidian, hugo, mkdocs, docusaurus)")
+	rootCmd.Flags().StringVar(&pathTemplateText, "path-template", naming.DefaultTemplate, "PDF/Markdown/音频/视频文件的路径模板, 支持占位符 {course} {chapter} {chapter_index} {index} {title} {id} {ext}, 数字可补零如 {index:03}, 重名时追加文章 ID")
+	rootCmd.Flags().IntVar(&imageMaxWidth, "image-max-width", 0, "Markdown/HTML/EPUB 图片的最大宽度(像素), 更宽的图片会等比缩小, 默认 0 不限制")
+	rootCmd.Flags().IntVar(&imageQuality, "image-quality", 0, "重新压缩 Markdown/HTML/EPUB 图片, JPEG 使用该质量(1 - 100), PNG 使用最高压缩级别, 默认 0 不压缩")
+	rootCmd.Flags().BoolVar(&imageWebPToPNG, "image-webp-to-png", false, "将 Markdown/HTML/EPUB 中的 WebP 图片转换为 PNG, 适合不支持 WebP 的电子书阅读器")
+	rootCmd.Flags().BoolVar(&downloadComments, "comments", false, "下载文章的全部评论(含回复和作者回复), 附加到 Markdown 末尾并保存为 comments.json, chrome 引擎生成的 PDF 包含第一页评论")
+	rootCmd.Flags().StringVar(&pdfEngine, "pdf-engine", pdf.EngineChrome, "PDF 生成引擎(chrome, native), native 引擎无需安装 Chrome, 企业版文章和训练营文字课时总是使用 native 引擎")
+	rootCmd.Flags().StringVar(&pdfFontPath, "pdf-font", "", "native 引擎使用的中文 TrueType 字体文件路径, 默认自动查找系统字体")
+	rootCmd.Flags().BoolVar(&pdfMerge, "pdf-merge", false, "课程下载完成后将所有文章 PDF 合并为一个带目录和书签的 PDF")
+	rootCmd.Flags().BoolVar(&audioMerge, "audio-merge", false, "课程下载完成后将所有文章音频合并为一个带章节和封面的 .m4b 文件, 音频仍为 MP3 编码(MP4 容器), VLC、mpv 和多数安卓有声书应用可播放, Apple Books、iTunes 等只支持 AAC 的播放器无法导入")
+	rootCmd.Flags().StringVar(&pdfPaper, "pdf-paper", "", "PDF 纸张大小(A3, A4, A5, B5, Letter, Legal)或自定义宽x高(毫米), 如 150x200, 默认 chrome 引擎 Letter, native 引擎 A4")
+	rootCmd.Flags().StringVar(&pdfMargin, "pdf-margin", "10.16", "PDF 页边距(毫米), 格式: 全部 | 上下,左右 | 上,右,下,左")
+	rootCmd.Flags().BoolVar(&pdfLandscape, "pdf-landscape", false, "PDF 横向打印")
+	rootCmd.Flags().Float64Var(&pdfScale, "pdf-scale", 1, "PDF 内容缩放比例(0.1 - 2)")
+	rootCmd.Flags().StringVar(&pdfHeader, "pdf-header", "", "PDF 页眉模板, 支持占位符 {title} {page} {pages} {date}")
+	rootCmd.Flags().StringVar(&pdfFooter, "pdf-footer", "", "PDF 页脚模板, 支持占位符 {title} {page} {pages} {date}, 如 \"{page} / {pages}\"")
+	rootCmd.Flags().BoolVar(&pdfBackground, "pdf-background", false, "PDF 打印背景色和背景图片")
+	rootCmd.Flags().StringVar(&pdfTheme, "pdf-theme", pdf.ThemeLight, "PDF 主题(light, dark)")
+	rootCmd.Flags().StringVar(&pdfDevice, "pdf-device", pdf.DefaultDevice, "chrome 引擎模拟的设备(ipad-pro-11, ipad-pro, ipad, ipad-mini, kindle-fire-hdx, galaxy-tab-s4, nexus-7, desktop)")
 }
 
 func setProductTypeOptions() {
 	if isEnterprise {
-		productTypeOptions = append(productTypeOptions, productTypeSelectOption{0, "训练营", 5, []string{"c44"}, true}) //custom source type, not use
+		productTypeOptions = append(productTypeOptions, productTypeSelectOption{0, "训练营/体系课/生态课", 5, []string{"c44"}, true}) //custom source type, not use
+		productTypeOptions = append(productTypeOptions, productTypeSelectOption{1, "每日一课", 2, []string{geektime.EnterpriseProductTypeDailyLesson}, false})
+		productTypeOptions = append(productTypeOptions, productTypeSelectOption{2, "大厂案例", 4, []string{geektime.EnterpriseProductTypeCase}, false})
 	} else {
 		productTypeOptions = append(productTypeOptions, productTypeSelectOption{0, "普通课程", 1, []string{"c1", "c3"}, true})
 		productTypeOptions = append(productTypeOptions, productTypeSelectOption{1, "每日一课", 2, []string{"d"}, false})
@@ -88,6 +148,30 @@ func setProductTypeOptions() {
 	}
 }
 
+// parsePDFLayout build pdf page layout from command line flags
+func parsePDFLayout() (pdf.PageLayout, error) {
+	layout := pdf.DefaultPageLayout()
+	width, height, err := pdf.ParsePaperSize(pdfPaper)
+	if err != nil {
+		return layout, err
+	}
+	margins, err := pdf.ParseMargins(pdfMargin)
+	if err != nil {
+		return layout, err
+	}
+	layout.PaperWidth = width
+	layout.PaperHeight = height
+	layout.Margins = margins
+	layout.Landscape = pdfLandscape
+	layout.Scale = pdfScale
+	layout.HeaderTemplate = pdfHeader
+	layout.FooterTemplate = pdfFooter
+	layout.PrintBackground = pdfBackground
+	layout.Theme = strings.ToLower(pdfTheme)
+	layout.Device = strings.ToLower(pdfDevice)
+	return layout, layout.Validate()
+}
+
 func logError(msg string) {
 	// 在下载目录下创建error.txt文件
 	errorFile := filepath.Join(downloadFolder, "error.txt")
@@ -113,6 +197,26 @@ var rootCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		ctx := cmd.Context()
 
+		if pdfEngine != pdf.EngineChrome && pdfEngine != pdf.EngineNative {
+			checkError(fmt.Errorf("不支持的 PDF 引擎: %s", pdfEngine))
+		}
+		if columnOutputType < 1 || columnOutputType > 31 {
+			checkError(fmt.Errorf("不支持的输出内容: %d", columnOutputType))
+		}
+		if isEnterprise && university {
+			checkError(errors.New("--enterprise 和 --university 不能同时使用"))
+		}
+
+		var err error
+		pdfLayout, err = parsePDFLayout()
+		checkError(err)
+		mdFlavor, err = markdown.ParseFlavor(mdFlavorName)
+		checkError(err)
+		pathTemplate, err = naming.Parse(pathTemplateText)
+		checkError(err)
+		images.DefaultOptions = images.Options{MaxWidth: imageMaxWidth, Quality: imageQuality, WebPToPNG: imageWebPToPNG}
+		checkError(images.DefaultOptions.Validate())
+
 		// 读取配置
 		cfg, err := config.GetConfig()
 		checkError(err)
@@ -178,26 +282,33 @@ var rootCmd = &cobra.Command{
 
 				fmt.Printf("\n正在获取课程信息, ID: %s\n", courseID)
 				// 加载课程信息
-				course, err := geektimeClient.CourseInfo(id)
+				course, err := loadConfiguredCourse(id)
 				if err != nil {
 					errMsg := fmt.Sprintf("获取课程信息失败: %s, 错误: %v", courseID, err)
 					fmt.Printf("%s\n", errMsg)
 					logError(errMsg)
 					return
 				}
+				if (isEnterprise || university) && !course.Access {
+					errMsg := fmt.Sprintf("尚未购买课程 %s, 跳过", course.Title)
+					fmt.Printf("%s\n", errMsg)
+					logError(errMsg)
+					return
+				}
 
-				// 跳过视频课程
-				if course.IsVideo {
+				// 跳过普通视频课程, 企业版视频课程和训练营下载视频
+				if course.IsVideo && !isEnterprise && !university {
 					errMsg := fmt.Sprintf("课程 %s 为视频课程,跳过", course.Title)
 					fmt.Printf("%s\n", errMsg)
 					logError(errMsg)
 					return
 				}
 
+				course = pathTemplate.Apply(course)
 				selectedProduct = course
 
 				// 创建课程目录
-				pdfDir, mdDir, err := mkDownloadProjectDir(downloadFolder, "", cfg.GCID, course.Title)
+				dirs, err := mkDownloadProjectDir(downloadFolder, "", cfg.GCID, course.Title)
 				if err != nil {
 					errMsg := fmt.Sprintf("创建目录失败: %v", err)
 					fmt.Printf("%s\n", errMsg)
@@ -206,6 +317,11 @@ var rootCmd = &cobra.Command{
 				}
 
 				fmt.Printf("开始下载课程: %s\n", course.Title)
+				if course.IsVideo {
+					downloadCourseVideos(ctx, course, dirs)
+					fmt.Printf("\n课程 %s 下载完成\n", course.Title)
+					return
+				}
 				total := len(course.Articles)
 				var count int
 
@@ -237,7 +353,7 @@ var rootCmd = &cobra.Command{
 								}
 							}()
 
-							skipped, err := downloadTextArticle(ctx, article, pdfDir, mdDir, false)
+							skipped, err := downloadTextArticle(ctx, article, dirs, false)
 							if err != nil {
 								if strings.Contains(err.Error(), "已触发限流") {
 									lastError = err.Error()
@@ -274,6 +390,8 @@ var rootCmd = &cobra.Command{
 					}
 				}
 
+				buildCourseOutputs(ctx, course, dirs)
+
 				fmt.Printf("\n课程 %s 下载完成\n", course.Title)
 			}()
 
@@ -329,6 +447,9 @@ func letInputProductID(ctx context.Context) {
 		// choose download all or download specified article
 		loadProduct(ctx, id)
 		productOps(ctx)
+	} else if isEnterprise {
+		downloadEnterpriseProduct(ctx, id)
+		letInputProductID(ctx)
 	} else {
 		// when product type is daily lesson or qconplus,
 		// input id means product id
@@ -342,14 +463,15 @@ func letInputProductID(ctx context.Context) {
 		}
 
 		if checkProductType(productInfo.Data.Info.Type) {
-			pdfDir, _, err := mkDownloadProjectDir(downloadFolder, phone, gcid, productInfo.Data.Info.Title)
+			dirs, err := mkDownloadProjectDir(downloadFolder, phone, gcid, productInfo.Data.Info.Title)
 			checkError(err)
 
 			err = video.DownloadArticleVideo(ctx,
 				geektimeClient,
 				productInfo.Data.Info.Article.ID,
 				selectedProductType.SourceType,
-				pdfDir,
+				dirs.pdf,
+				"",
 				quality,
 				concurrency)
 
@@ -359,6 +481,103 @@ func letInputProductID(ctx context.Context) {
 	}
 }
 
+// downloadEnterpriseProduct download videos of enterprise daily lesson or case study, text and
+// summary of articles are saved as markdown
+func downloadEnterpriseProduct(ctx context.Context, productID int) {
+	p, err := geektimeClient.EnterpriseCourseInfo(productID)
+	checkError(err)
+	if !checkProductType(p.Type) {
+		return
+	}
+	if !p.Access {
+		fmt.Fprint(os.Stderr, "尚未购买该课程\n")
+		return
+	}
+	p = pathTemplate.Apply(p)
+	selectedProduct = p
+
+	dirs, err := mkDownloadProjectDir(downloadFolder, phone, gcid, p.Title)
+	checkError(err)
+	downloadCourseVideos(ctx, p, dirs)
+	fmt.Printf("\r%s 下载完成\n", p.Title)
+}
+
+// loadConfiguredCourse load course of ID in config, which is enterprise course with --enterprise
+// and university class with --university
+func loadConfiguredCourse(id int) (geektime.Course, error) {
+	if isEnterprise {
+		return geektimeClient.EnterpriseCourseInfo(id)
+	}
+	if university {
+		return geektimeClient.UniversityCourseInfo(id)
+	}
+	return geektimeClient.CourseInfo(id)
+}
+
+// downloadCourseVideos download all videos of video course, text lessons of university course are
+// saved as pdf and markdown, text or summary of enterprise videos as markdown. Errors are logged
+// and do not stop downloading other articles.
+func downloadCourseVideos(ctx context.Context, course geektime.Course, dirs courseDirs) {
+	total := len(course.Articles)
+	var count int
+	for _, article := range course.Articles {
+		var skipped bool
+		var err error
+		if article.IsText {
+			skipped, err = downloadUniversityArticleText(ctx, article, dirs, false)
+		} else {
+			skipped, err = saveArticleVideo(ctx, article, dirs.pdf, false)
+		}
+		if err != nil {
+			errMsg := fmt.Sprintf("课时 %s 下载失败: %v", article.Title, err)
+			fmt.Printf("\n%s\n", errMsg)
+			logError(errMsg)
+		}
+		if isEnterprise {
+			if err := downloadEnterpriseArticleText(ctx, article, dirs); err != nil {
+				errMsg := fmt.Sprintf("保存文章 %s 失败: %v", article.Title, err)
+				fmt.Printf("\n%s\n", errMsg)
+				logError(errMsg)
+			}
+		}
+		increaseDownloadedTextArticleCount(total, &count)
+		if !skipped {
+			waitRandomTime()
+		}
+	}
+	// videos of normal video courses have no markdown
+	if isEnterprise || isUniversity() {
+		buildCourseMarkdown(course, dirs.markdown)
+	}
+}
+
+// downloadEnterpriseArticleText save text or summary of enterprise video article as markdown
+// when markdown output is selected
+func downloadEnterpriseArticleText(ctx context.Context, article geektime.Article, dirs courseDirs) error {
+	if columnOutputType&2 != 2 || mdFlavor.ArticleExists(dirs.markdown, article.FileName()) {
+		return nil
+	}
+	articleInfo, err := geektimeClient.EnterpriseArticleInfo(article.AID)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(articleInfo.Data.ArticleContent) == "" && strings.TrimSpace(articleInfo.ContentMD) == "" {
+		return markdown.MarkNoContent(dirs.markdown, article.FileName())
+	}
+	_, err = markdown.Download(ctx, markdown.DownloadOptions{
+		HTML:        articleInfo.Data.ArticleContent,
+		NativeMD:    articleInfo.ContentMD,
+		Title:       article.Title,
+		Name:        article.FileName(),
+		Dir:         dirs.markdown,
+		VideoDir:    video.MP4Dir(dirs.pdf, article.FileName()),
+		FrontMatter: markdownFrontMatter(article, articleInfo.V1ArticleResponse, dirs, false),
+		Flavor:      mdFlavor,
+	})
+	reportImageFailures(article)
+	return err
+}
+
 func loadProduct(ctx context.Context, productID int) {
 	sp.Prefix = "[ 正在加载课程信息... ]"
 	sp.Start()
@@ -369,7 +588,7 @@ func loadProduct(ctx context.Context, productID int) {
 		// if input invalid id, access mark is 0
 		p, err = geektimeClient.UniversityCourseInfo(productID)
 	} else if isEnterprise {
-		// TODO: check enterprise course type
+		// enterprise course may be text or video course, detected by its articles
 		p, err = geektimeClient.EnterpriseCourseInfo(productID)
 	} else {
 		p, err = geektimeClient.CourseInfo(productID)
@@ -392,7 +611,7 @@ func loadProduct(ctx context.Context, productID int) {
 		fmt.Fprint(os.Stderr, "尚未购买该课程\n")
 		letInputProductID(ctx)
 	}
-	selectedProduct = p
+	selectedProduct = pathTemplate.Apply(p)
 }
 
 func productOps(ctx context.Context) {
@@ -405,6 +624,10 @@ func productOps(ctx context.Context) {
 		options[1] = articleOpsOption{"下载所有视频", 1}
 		options[2] = articleOpsOption{"选择视频", 2}
 	}
+	if isUniversity() {
+		options[1] = articleOpsOption{"下载所有课时", 1}
+		options[2] = articleOpsOption{"选择课时", 2}
+	}
 	templates := &promptui.SelectTemplates{
 		Label:    "{{ . }}",
 		Active:   "{{ `>` | red }} {{ .Text | red }}",
@@ -465,11 +688,11 @@ func handleSelectArticle(ctx context.Context, index int) {
 	a := selectedProduct.Articles[index-1]
 
 	// 创建目录
-	pdfDir, mdDir, err := mkDownloadProjectDir(downloadFolder, phone, gcid, selectedProduct.Title)
+	dirs, err := mkDownloadProjectDir(downloadFolder, phone, gcid, selectedProduct.Title)
 	checkError(err)
 
 	// 修改 downloadArticle 调用
-	downloadArticle(ctx, a, pdfDir, mdDir)
+	downloadArticle(ctx, a, dirs)
 	fmt.Printf("\r%s 下载完成", a.Title)
 	time.Sleep(time.Second)
 	selectArticle(ctx)
@@ -477,7 +700,7 @@ func handleSelectArticle(ctx context.Context, index int) {
 
 func handleDownloadAll(ctx context.Context) {
 	// 创建目录
-	pdfDir, mdDir, err := mkDownloadProjectDir(downloadFolder, phone, gcid, selectedProduct.Title)
+	dirs, err := mkDownloadProjectDir(downloadFolder, phone, gcid, selectedProduct.Title)
 	checkError(err)
 
 	if isText() {
@@ -486,7 +709,7 @@ func handleDownloadAll(ctx context.Context) {
 		var count int
 
 		for _, article := range selectedProduct.Articles {
-			skipped, err := downloadTextArticle(ctx, article, pdfDir, mdDir, false)
+			skipped, err := downloadTextArticle(ctx, article, dirs, false)
 			if err != nil {
 				fmt.Printf("下载文章失败: %v\n", err)
 				continue
@@ -498,13 +721,10 @@ func handleDownloadAll(ctx context.Context) {
 				waitRandomTime()
 			}
 		}
+
+		buildCourseOutputs(ctx, selectedProduct, dirs)
 	} else {
-		for _, article := range selectedProduct.Articles {
-			skipped := downloadVideoArticle(ctx, article, pdfDir, false)
-			if !skipped {
-				waitRandomTime()
-			}
-		}
+		downloadCourseVideos(ctx, selectedProduct, dirs)
 	}
 	selectProductType(ctx)
 }
@@ -514,61 +734,169 @@ func increaseDownloadedTextArticleCount(total int, i *int) {
 	fmt.Printf("\r已完成下载%d/%d", *i, total)
 }
 
-func downloadArticle(ctx context.Context, article geektime.Article, pdfDir, mdDir string) {
+func downloadArticle(ctx context.Context, article geektime.Article, dirs courseDirs) {
 	if isText() {
 		sp.Prefix = fmt.Sprintf("[ 正在下载 《%s》... ]", article.Title)
 		sp.Start()
 		defer sp.Stop()
-		skipped, err := downloadTextArticle(ctx, article, pdfDir, mdDir, true)
+		skipped, err := downloadTextArticle(ctx, article, dirs, true)
 		if err != nil {
 			fmt.Printf("下载文章失败: %v\n", err)
 		}
 		if !skipped {
 			waitRandomTime()
 		}
+	} else if article.IsText {
+		if _, err := downloadUniversityArticleText(ctx, article, dirs, true); err != nil {
+			fmt.Printf("保存文章失败: %v\n", err)
+		}
 	} else {
-		downloadVideoArticle(ctx, article, pdfDir, true)
+		downloadVideoArticle(ctx, article, dirs.pdf, true)
 	}
 }
 
-func downloadTextArticle(ctx context.Context, article geektime.Article, pdfDir, mdDir string, overwrite bool) (bool, error) {
-	needDownloadPDF := columnOutputType&1 == 1
+// downloadUniversityArticleText save text lesson of university course as markdown and pdf, files
+// are named by path template like videos
+func downloadUniversityArticleText(ctx context.Context, article geektime.Article, dirs courseDirs, overwrite bool) (bool, error) {
+	// university lesson pages can not be printed by chrome engine
+	needDownloadPDF := columnOutputType&1 == 1 && canRenderNativePDF()
 	needDownloadMD := (columnOutputType>>1)&1 == 1
+	pdfExists := files.CheckFileExists(filepath.Join(dirs.pdf, article.FileName()+pdf.PDFExtension))
+	mdExists := files.CheckFileExists(mdFlavor.ArticlePath(dirs.markdown, article.FileName()))
+	if !overwrite && (!needDownloadPDF || pdfExists) && (!needDownloadMD || mdExists) {
+		return true, nil
+	}
+
+	articleInfo, err := geektimeClient.UniversityArticleInfo(article.AID, selectedProduct.ID)
+	if err != nil {
+		return false, fmt.Errorf("获取文章信息失败: %v", err)
+	}
+	if strings.TrimSpace(articleInfo.Data.ArticleContent) == "" {
+		return false, nil
+	}
+
+	if needDownloadMD && (overwrite || !mdExists) {
+		_, err = markdown.Download(ctx, markdown.DownloadOptions{
+			HTML:        articleInfo.Data.ArticleContent,
+			Title:       article.Title,
+			Name:        article.FileName(),
+			Dir:         dirs.markdown,
+			FrontMatter: markdownFrontMatter(article, articleInfo, dirs, false),
+			Flavor:      mdFlavor,
+			Overwrite:   overwrite,
+		})
+		reportImageFailures(article)
+		if err != nil {
+			return false, fmt.Errorf("生成Markdown失败: %v", err)
+		}
+	}
+
+	if needDownloadPDF && (overwrite || !pdfExists) {
+		_, err = pdf.RenderArticleToPDF(ctx,
+			articleInfo.Data.ArticleContent,
+			dirs.pdf,
+			article.FileName(),
+			article.Title,
+			pdfFontPath,
+			pdfLayout,
+			overwrite,
+		)
+		reportImageFailures(article)
+		if err != nil {
+			return false, fmt.Errorf("生成PDF失败: %v", err)
+		}
+	}
+	return false, nil
+}
+
+func downloadTextArticle(ctx context.Context, article geektime.Article, dirs courseDirs, overwrite bool) (bool, error) {
+	// enterprise article pages can not be printed by chrome engine
+	needDownloadPDF := columnOutputType&1 == 1 && (!isEnterprise || canRenderNativePDF())
+	needDownloadMD := (columnOutputType>>1)&1 == 1
+	needDownloadAudio := (columnOutputType>>2)&1 == 1
+	needDownloadEPUB := (columnOutputType>>3)&1 == 1
+	needDownloadHTML := (columnOutputType>>4)&1 == 1
+	// comments of enterprise articles are not available
+	withComments := downloadComments && !isEnterprise
 
 	// 检查文件是否已存在
 	pdfExists := false
 	mdExists := false
 
 	if needDownloadPDF {
-		pdfPath := filepath.Join(pdfDir, filenamify.Filenamify(article.Title)+".pdf")
+		pdfPath := filepath.Join(dirs.pdf, article.FileName()+pdf.PDFExtension)
 		if _, err := os.Stat(pdfPath); err == nil {
 			pdfExists = true
 		}
 	}
 
 	if needDownloadMD {
-		mdPath := filepath.Join(mdDir, filenamify.Filenamify(article.Title)+".md")
+		mdPath := mdFlavor.ArticlePath(dirs.markdown, article.FileName())
 		if _, err := os.Stat(mdPath); err == nil {
 			mdExists = true
 		}
 	}
 
+	audioExists := false
+	if needDownloadAudio {
+		audioExists = audio.Exists(dirs.audio, article.FileName())
+	}
+
+	epubExists := false
+	if needDownloadEPUB {
+		epubExists = files.CheckFileExists(filepath.Join(dirs.epub, epub.ArticleFileName(article.AID)))
+	}
+
+	htmlExists := false
+	if needDownloadHTML {
+		htmlExists = files.CheckFileExists(filepath.Join(dirs.html, site.ArticleFileName(article.AID)))
+	}
+
+	commentsExists := false
+	if withComments {
+		commentsExists = files.CheckFileExists(commentsFilePath(dirs.markdown, article.AID))
+	}
+
+	// video articles of enterprise text courses are downloaded along with the text
+	videoExists := !isEnterprise || article.IsText ||
+		files.CheckFileExists(filepath.Join(dirs.pdf, article.FileName()+video.TSExtension))
+
 	// 如果所有需要的文件都存在，直接跳过
-	if (!needDownloadPDF || pdfExists) && (!needDownloadMD || mdExists) {
+	if (!needDownloadPDF || pdfExists) && (!needDownloadMD || mdExists) && (!needDownloadAudio || audioExists) &&
+		(!needDownloadEPUB || epubExists) && (!needDownloadHTML || htmlExists) && (!withComments || commentsExists) &&
+		videoExists {
 		fmt.Printf("\n文章 %s 已存在，跳过下载\n", article.Title)
 		return true, nil
 	}
 
+	// comments are requested for an existing archive, rewrite text files to include them
+	rewriteText := overwrite
+	if withComments && !commentsExists {
+		rewriteText = true
+		mdExists, epubExists, htmlExists = false, false, false
+	}
+
 	// 获取文章信息
-	articleInfo, err := geektimeClient.V1ArticleInfo(article.AID)
+	articleInfo, nativeMD, err := textArticleInfo(article.AID)
 	if err != nil {
 		return false, fmt.Errorf("获取文章信息失败: %v", err)
 	}
 
+	var comments []geektime.Comment
+	if withComments {
+		comments, err = geektimeClient.ArticleComments(article.AID)
+		if err != nil {
+			return false, fmt.Errorf("获取文章评论失败: %v", err)
+		}
+		if err := saveComments(comments, dirs.markdown, article.AID); err != nil {
+			return false, fmt.Errorf("保存文章评论失败: %v", err)
+		}
+	}
+
 	// 处理视频内容
 	hasVideo, videoURL := getVideoURLFromArticleContent(articleInfo.Data.ArticleContent)
 	if hasVideo && videoURL != "" {
-		err = video.DownloadMP4(ctx, article.Title, pdfDir, []string{videoURL}, overwrite)
+		err = video.DownloadMP4(ctx, article.FileName(), dirs.pdf, []string{videoURL}, overwrite)
 		if err != nil {
 			return false, fmt.Errorf("下载视频失败: %v", err)
 		}
@@ -579,24 +907,47 @@ func downloadTextArticle(ctx context.Context, article geektime.Article, pdfDir,
 		for i, v := range articleInfo.Data.InlineVideoSubtitles {
 			videoURLs[i] = v.VideoURL
 		}
-		err = video.DownloadMP4(ctx, article.Title, pdfDir, videoURLs, overwrite)
+		err = video.DownloadMP4(ctx, article.FileName(), dirs.pdf, videoURLs, overwrite)
 		if err != nil {
 			return false, fmt.Errorf("下载内嵌视频失败: %v", err)
 		}
 	}
 
+	// 企业版文字课程中的视频文章
+	if isEnterprise && articleInfo.Data.VideoID != "" && (overwrite || !videoExists) {
+		err = video.DownloadEnterpriseArticleVideo(ctx, geektimeClient, article.AID, dirs.pdf, article.FileName(), quality, concurrency)
+		if err != nil {
+			return false, fmt.Errorf("下载视频失败: %v", err)
+		}
+	}
+
 	// 只下载不存在的 PDF 文件
 	if needDownloadPDF && !pdfExists {
-		_, err := pdf.PrintArticlePageToPDF(ctx,
-			article.AID,
-			pdfDir,
-			article.Title,
-			geektimeClient.Cookies,
-			downloadComments,
-			printPDFWaitSeconds,
-			printPDFTimeoutSeconds,
-			overwrite,
-		)
+		var err error
+		if pdfEngine == pdf.EngineNative || isEnterprise {
+			_, err = pdf.RenderArticleToPDF(ctx,
+				articleInfo.Data.ArticleContent,
+				dirs.pdf,
+				article.FileName(),
+				article.Title,
+				pdfFontPath,
+				pdfLayout,
+				overwrite,
+			)
+		} else {
+			_, err = pdf.PrintArticlePageToPDF(ctx,
+				article.AID,
+				dirs.pdf,
+				article.FileName(),
+				article.Title,
+				geektimeClient.Cookies,
+				downloadComments,
+				printPDFWaitSeconds,
+				printPDFTimeoutSeconds,
+				pdfLayout,
+				overwrite,
+			)
+		}
 		if err != nil {
 			return false, fmt.Errorf("生成PDF失败: %v", err)
 		}
@@ -604,46 +955,363 @@ func downloadTextArticle(ctx context.Context, article geektime.Article, pdfDir,
 
 	// 只下载不存在的 Markdown 文件
 	if needDownloadMD && !mdExists {
-		_, err := markdown.Download(ctx,
-			articleInfo.Data.ArticleContent,
+		_, err := markdown.Download(ctx, markdown.DownloadOptions{
+			HTML:        articleInfo.Data.ArticleContent,
+			NativeMD:    nativeMD,
+			Title:       article.Title,
+			Name:        article.FileName(),
+			Dir:         dirs.markdown,
+			VideoDir:    video.MP4Dir(dirs.pdf, article.FileName()),
+			Comments:    comments,
+			FrontMatter: markdownFrontMatter(article, articleInfo, dirs, needDownloadAudio),
+			Flavor:      mdFlavor,
+			Resolver:    courseLinkResolver(),
+			Overwrite:   rewriteText,
+		})
+		if err != nil {
+			return false, fmt.Errorf("生成Markdown失败: %v", err)
+		}
+	}
+
+	// 只生成不存在的 EPUB 章节
+	if needDownloadEPUB && !epubExists {
+		_, err := epub.SaveArticle(ctx,
+			dirs.epub,
 			article.Title,
-			mdDir,
 			article.AID,
-			overwrite)
+			articleInfo.Data.ArticleContent,
+			comments,
+			courseLinkResolver(),
+			rewriteText)
 		if err != nil {
-			return false, fmt.Errorf("生成Markdown失败: %v", err)
+			return false, fmt.Errorf("生成EPUB章节失败: %v", err)
+		}
+	}
+
+	// 只生成不存在的 HTML 页面
+	if needDownloadHTML && !htmlExists {
+		_, err := site.SaveArticle(ctx,
+			dirs.html,
+			selectedProduct,
+			article.AID,
+			article.Title,
+			articleInfo.Data.ArticleContent,
+			comments,
+			courseLinkResolver(),
+			rewriteText)
+		if err != nil {
+			return false, fmt.Errorf("生成HTML页面失败: %v", err)
 		}
 	}
 
+	// 只下载不存在的音频文件
+	if needDownloadAudio && !audioExists {
+		if articleInfo.Data.AudioDownloadURL == "" {
+			logger.Warnf("Article %s has no audio", article.Title)
+			if err := audio.MarkNoAudio(dirs.audio, article.FileName()); err != nil {
+				return false, fmt.Errorf("记录无音频文章失败: %v", err)
+			}
+		} else if _, err := audio.DownloadAudio(ctx,
+			articleInfo.Data.AudioDownloadURL,
+			dirs.audio,
+			article.FileName(),
+			audioTags(article, articleInfo),
+			overwrite); err != nil {
+			return false, fmt.Errorf("下载音频失败: %v", err)
+		}
+	}
+
+	reportImageFailures(article)
 	return false, nil
 }
 
-func downloadVideoArticle(ctx context.Context, article geektime.Article, projectDir string, overwrite bool) bool {
-	dir := projectDir
-	var err error
-	// add sub dir
-	if article.SectionTitle != "" {
-		dir, err = mkDownloadProjectSectionDir(projectDir, article.SectionTitle)
-		checkError(err)
+// textArticleInfo get article of normal or enterprise text course, along with native markdown of
+// enterprise article
+func textArticleInfo(aid int) (response.V1ArticleResponse, string, error) {
+	if isEnterprise {
+		info, err := geektimeClient.EnterpriseArticleInfo(aid)
+		return info.V1ArticleResponse, info.ContentMD, err
 	}
+	info, err := geektimeClient.V1ArticleInfo(aid)
+	return info, "", err
+}
 
-	fileName := filenamify.Filenamify(article.Title) + video.TSExtension
-	fullPath := filepath.Join(dir, fileName)
-	if files.CheckFileExists(fullPath) && !overwrite {
+// canRenderNativePDF report whether a CJK font is available for articles which can only be
+// rendered by native engine, their PDF is skipped with a warning otherwise
+func canRenderNativePDF() bool {
+	if pdfFontPath != "" || pdf.FindCJKFont() != "" {
 		return true
 	}
+	if !nativePDFWarned {
+		nativePDFWarned = true
+		msg := fmt.Sprintf("%v, 企业版文章和训练营文字课时只能使用 native 引擎生成 PDF, 已跳过它们的 PDF", pdf.ErrCJKFontNotFound)
+		fmt.Printf("\n%s\n", msg)
+		logError(msg)
+	}
+	return false
+}
+
+// reportImageFailures warn images of article which were replaced with placeholder
+func reportImageFailures(article geektime.Article) {
+	for _, f := range images.TakeFailures() {
+		msg := fmt.Sprintf("文章 %s 的图片 %s 下载失败, 已使用占位图替代: %v", article.Title, f.URL, f.Err)
+		fmt.Printf("\n%s\n", msg)
+		logError(msg)
+	}
+}
+
+// markdownFrontMatter build front matter of article markdown when --md-front-matter is set, flavors
+// which order pages by front matter always get title and position
+func markdownFrontMatter(article geektime.Article, articleInfo response.V1ArticleResponse, dirs courseDirs, withAudio bool) *markdown.FrontMatter {
+	var position int
+	for i, a := range selectedProduct.Articles {
+		if a.AID == article.AID {
+			position = i + 1
+			break
+		}
+	}
+	if !mdFrontMatter {
+		if !mdFlavor.NeedsFrontMatter() {
+			return nil
+		}
+		return &markdown.FrontMatter{Title: article.Title, ArticleID: article.AID, Position: position}
+	}
+	fm := &markdown.FrontMatter{
+		Title:     article.Title,
+		ArticleID: article.AID,
+		CourseID:  selectedProduct.ID,
+		Course:    selectedProduct.Title,
+		Chapter:   article.SectionTitle,
+		Author:    articleInfo.Data.AuthorName,
+		Tags:      []string{"极客时间", selectedProduct.Title},
+		Position:  position,
+	}
+	if fm.Author == "" {
+		fm.Author = selectedProduct.Author
+	}
+	if !isEnterprise {
+		// enterprise articles are only readable in enterprise site
+		fm.Source = geektime.DefaultBaseURL + "/column/article/" + strconv.Itoa(article.AID)
+	}
+	if articleInfo.Data.ArticleCtime > 0 {
+		fm.Date = time.Unix(int64(articleInfo.Data.ArticleCtime), 0)
+	}
+	if withAudio && articleInfo.Data.AudioDownloadURL != "" {
+		audioFile := filepath.Join(dirs.audio, article.FileName()+audio.MP3Extension)
+		mdDir := filepath.Dir(mdFlavor.ArticlePath(dirs.markdown, article.FileName()))
+		if rel, err := filepath.Rel(mdDir, audioFile); err == nil {
+			fm.Audio = filepath.ToSlash(rel)
+		}
+	}
+	return fm
+}
+
+// audioTags build ID3 tags, track number is article position in selected course
+func audioTags(article geektime.Article, articleInfo response.V1ArticleResponse) audio.Tags {
+	tags := audio.Tags{
+		Title:       article.Title,
+		Album:       selectedProduct.Title,
+		Artist:      articleInfo.Data.AudioDubber,
+		AlbumArtist: selectedProduct.Author,
+		TrackTotal:  len(selectedProduct.Articles),
+		CoverURL:    articleInfo.Data.ColumnCover,
+		Summary:     strings.TrimSpace(articleInfo.Data.ArticleSummary),
+	}
+	if tags.Artist == "" {
+		tags.Artist = selectedProduct.Author
+	}
+	for i, a := range selectedProduct.Articles {
+		if a.AID == article.AID {
+			tags.Track = i + 1
+			break
+		}
+	}
+	if articleInfo.Data.ArticleCtime > 0 {
+		tags.Year = time.Unix(int64(articleInfo.Data.ArticleCtime), 0).Year()
+	}
+	return tags
+}
+
+// commentsFilePath comments/aid/comments.json in markdown dir, next to images/aid
+func commentsFilePath(mdDir string, aid int) string {
+	return filepath.Join(mdDir, "comments", strconv.Itoa(aid), "comments.json")
+}
+
+func saveComments(comments []geektime.Comment, mdDir string, aid int) error {
+	fileName := commentsFilePath(mdDir, aid)
+	if err := os.MkdirAll(filepath.Dir(fileName), os.ModePerm); err != nil {
+		return err
+	}
+	if comments == nil {
+		comments = []geektime.Comment{}
+	}
+	data, err := json.MarshalIndent(comments, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, data, 0644)
+}
+
+// courseLinkResolver return link resolver of selected course, links to articles of the course
+// and courses downloaded before are rewritten to local files
+func courseLinkResolver() *links.Resolver {
+	if linkResolver == nil || linkResolver.CourseID() != selectedProduct.ID {
+		linkResolver = links.NewResolver(downloadFolder, selectedProduct)
+	}
+	return linkResolver
+}
+
+// reportMissingLinks print and save links to articles not downloaded yet, then add course to
+// manifest so that later courses can link to it
+func reportMissingLinks(course geektime.Course, dirs courseDirs) {
+	if err := links.SaveManifest(downloadFolder, course); err != nil {
+		logError(fmt.Sprintf("保存文章清单失败: %v", err))
+	}
+	if linkResolver == nil || linkResolver.CourseID() != course.ID {
+		return
+	}
+	missing := linkResolver.Missing(func(r links.Record) bool {
+		t := r.Target
+		return files.CheckFileExists(mdFlavor.ArticlePath(filepath.Join(downloadFolder, "markdown", t.Course), t.FileName())) ||
+			files.CheckFileExists(filepath.Join(downloadFolder, "html", t.Course, site.ArticleFileName(r.AID))) ||
+			files.CheckFileExists(filepath.Join(downloadFolder, "epub", t.Course, epub.ArticleFileName(r.AID)))
+	})
+	fileName := filepath.Join(dirs.markdown, missingLinksFileName)
+	if len(missing) == 0 {
+		_ = os.Remove(fileName)
+		return
+	}
+	var sb strings.Builder
+	for _, m := range missing {
+		target := "未下载的课程"
+		if m.Known {
+			target = m.Target.Course + " / " + m.Target.Title
+		}
+		sb.WriteString(m.Source + "\t" + m.URL + "\t" + target + "\n")
+	}
+	if err := os.WriteFile(fileName, []byte(sb.String()), 0644); err != nil {
+		logError(fmt.Sprintf("保存链接报告失败: %v", err))
+	}
+	fmt.Printf("\n课程 %s 中有 %d 个链接指向尚未下载的文章, 详见 %s\n", course.Title, len(missing), fileName)
+}
+
+// buildCourseOutputs build course level outputs after all articles downloaded
+func buildCourseOutputs(ctx context.Context, course geektime.Course, dirs courseDirs) {
+	reportMissingLinks(course, dirs)
+	buildCourseMarkdown(course, dirs.markdown)
+	mergeCoursePDF(ctx, course, dirs.pdf)
+	mergeCourseAudio(ctx, course, dirs.audio)
+	buildCourseEPUB(ctx, course, dirs.epub)
+	buildCourseSite(course, dirs.html)
+	updateSearchIndex()
+}
+
+// updateSearchIndex index newly downloaded markdown once index is built by index command
+func updateSearchIndex() {
+	if columnOutputType&2 != 2 || !search.Exists(downloadFolder) {
+		return
+	}
+	if _, err := search.Update(downloadFolder, false); err != nil {
+		errMsg := fmt.Sprintf("更新搜索索引失败: %v", err)
+		fmt.Printf("\n%s\n", errMsg)
+		logError(errMsg)
+	}
+}
+
+func buildCourseSite(course geektime.Course, htmlDir string) {
+	if columnOutputType&16 != 16 {
+		return
+	}
+	err := site.WriteCourseIndex(course, htmlDir)
+	if err == nil {
+		err = site.WriteLibraryIndex(filepath.Dir(htmlDir))
+	}
+	if err != nil {
+		errMsg := fmt.Sprintf("生成课程 %s 的 HTML 目录失败: %v", course.Title, err)
+		fmt.Printf("\n%s\n", errMsg)
+		logError(errMsg)
+		return
+	}
+	fmt.Printf("\n课程 %s 的 HTML 已生成: %s\n", course.Title, filepath.Join(htmlDir, site.IndexFileName))
+}
+
+func buildCourseMarkdown(course geektime.Course, mdDir string) {
+	if columnOutputType&2 != 2 {
+		return
+	}
+	if err := markdown.WriteCourseFiles(course, mdDir, mdFlavor); err != nil {
+		errMsg := fmt.Sprintf("生成课程 %s 的 Markdown 目录文件失败: %v", course.Title, err)
+		fmt.Printf("\n%s\n", errMsg)
+		logError(errMsg)
+	}
+}
+
+func buildCourseEPUB(ctx context.Context, course geektime.Course, epubDir string) {
+	if columnOutputType&8 != 8 {
+		return
+	}
+	out, err := epub.Build(ctx, course, epubDir)
+	if err != nil {
+		errMsg := fmt.Sprintf("生成课程 %s 的 EPUB 失败: %v", course.Title, err)
+		fmt.Printf("\n%s\n", errMsg)
+		logError(errMsg)
+		return
+	}
+	fmt.Printf("\n课程 %s 的 EPUB 已生成: %s\n", course.Title, out)
+}
+
+func mergeCourseAudio(ctx context.Context, course geektime.Course, audioDir string) {
+	if !audioMerge || columnOutputType&4 != 4 {
+		return
+	}
+	out, err := audio.MergeCourseAudio(ctx, audioDir, course.Title, course.Author)
+	if err != nil {
+		errMsg := fmt.Sprintf("生成课程 %s 的有声书失败: %v", course.Title, err)
+		fmt.Printf("\n%s\n", errMsg)
+		logError(errMsg)
+		return
+	}
+	fmt.Printf("\n课程 %s 的有声书已生成: %s\n", course.Title, out)
+}
+
+// mergeCoursePDF merge all article pdfs of course into one pdf when --pdf-merge is set
+func mergeCoursePDF(ctx context.Context, course geektime.Course, pdfDir string) {
+	if !pdfMerge || columnOutputType&1 != 1 {
+		return
+	}
+	out, err := pdf.MergeCoursePDF(ctx, course, pdfDir, pdfFontPath, pdfLayout)
+	if err != nil {
+		errMsg := fmt.Sprintf("合并课程 %s 的 PDF 失败: %v", course.Title, err)
+		fmt.Printf("\n%s\n", errMsg)
+		logError(errMsg)
+		return
+	}
+	fmt.Printf("\n课程 %s 的 PDF 已合并至 %s\n", course.Title, out)
+	if pdfFontPath == "" && pdf.FindCJKFont() == "" {
+		fmt.Println("未找到中文 TrueType 字体, 合并的 PDF 不包含封面和目录页, 可通过 --pdf-font 指定字体文件路径")
+	}
+}
+
+func downloadVideoArticle(ctx context.Context, article geektime.Article, projectDir string, overwrite bool) bool {
+	skipped, err := saveArticleVideo(ctx, article, projectDir, overwrite)
+	checkError(err)
+	return skipped
+}
+
+// saveArticleVideo download video of article, existing video is skipped unless overwrite
+func saveArticleVideo(ctx context.Context, article geektime.Article, projectDir string, overwrite bool) (bool, error) {
+	name := article.FileName()
+	fullPath := filepath.Join(projectDir, name+video.TSExtension)
+	if files.CheckFileExists(fullPath) && !overwrite {
+		return true, nil
+	}
 
 	if isUniversity() {
-		err = video.DownloadUniversityVideo(ctx, geektimeClient, article.AID, selectedProduct, dir, quality, concurrency)
-		checkError(err)
+		return false, video.DownloadUniversityVideo(ctx, geektimeClient, article.AID, selectedProduct, projectDir, name, quality, concurrency)
 	} else if isEnterprise {
-		err = video.DownloadEnterpriseArticleVideo(ctx, geektimeClient, article.AID, dir, quality, concurrency)
-		checkError(err)
-	} else {
-		err = video.DownloadArticleVideo(ctx, geektimeClient, article.AID, selectedProductType.SourceType, dir, quality, concurrency)
-		checkError(err)
+		return false, video.DownloadEnterpriseArticleVideo(ctx, geektimeClient, article.AID, projectDir, name, quality, concurrency)
 	}
-	return false
+	return false, video.DownloadArticleVideo(ctx, geektimeClient, article.AID, selectedProductType.SourceType, projectDir, name, quality, concurrency)
 }
 
 func isText() bool {
@@ -651,7 +1319,7 @@ func isText() bool {
 }
 
 func isUniversity() bool {
-	return selectedProductType.Index == 4 && !isEnterprise
+	return university || (selectedProductType.Index == 4 && !isEnterprise)
 }
 
 func readCookiesFromInput() []*http.Cookie {
@@ -674,29 +1342,62 @@ func readCookiesFromInput() []*http.Cookie {
 	return cookies
 }
 
-func mkDownloadProjectDir(downloadFolder, phone, gcid, projectName string) (string, string, error) {
+// missingLinksFileName is report of links to articles not downloaded, saved in markdown dir
+const missingLinksFileName = "missing-links.txt"
+
+// courseDirs is output dirs of one course
+type courseDirs struct {
+	pdf      string
+	markdown string
+	audio    string
+	// epub work dir, articles are saved as xhtml here and packaged after course downloaded
+	epub string
+	// html site dir, browsable from file://
+	html string
+}
+
+func mkDownloadProjectDir(downloadFolder, phone, gcid, projectName string) (courseDirs, error) {
+	name := filenamify.Filenamify(projectName)
+	dirs := courseDirs{
+		pdf:      filepath.Join(downloadFolder, "pdf", name),
+		markdown: filepath.Join(downloadFolder, "markdown", name),
+		audio:    filepath.Join(downloadFolder, "audio", name),
+		epub:     filepath.Join(downloadFolder, "epub", name),
+		html:     filepath.Join(downloadFolder, "html", name),
+	}
+
 	// 创建 PDF 目录
-	pdfPath := filepath.Join(downloadFolder, "pdf", filenamify.Filenamify(projectName))
-	if err := os.MkdirAll(pdfPath, os.ModePerm); err != nil {
-		return "", "", err
+	if err := os.MkdirAll(dirs.pdf, os.ModePerm); err != nil {
+		return dirs, err
 	}
 
 	// 创建 Markdown 目录
-	mdPath := filepath.Join(downloadFolder, "markdown", filenamify.Filenamify(projectName))
-	if err := os.MkdirAll(mdPath, os.ModePerm); err != nil {
-		return "", "", err
+	if err := os.MkdirAll(dirs.markdown, os.ModePerm); err != nil {
+		return dirs, err
 	}
 
-	return pdfPath, mdPath, nil
-}
+	// 创建音频目录
+	if columnOutputType&4 == 4 {
+		if err := os.MkdirAll(dirs.audio, os.ModePerm); err != nil {
+			return dirs, err
+		}
+	}
 
-func mkDownloadProjectSectionDir(downloadFolder, sectionName string) (string, error) {
-	path := filepath.Join(downloadFolder, filenamify.Filenamify(sectionName))
-	err := os.MkdirAll(path, os.ModePerm)
-	if err != nil {
-		return "", err
+	// 创建 EPUB 目录
+	if columnOutputType&8 == 8 {
+		if err := os.MkdirAll(dirs.epub, os.ModePerm); err != nil {
+			return dirs, err
+		}
 	}
-	return path, nil
+
+	// 创建 HTML 目录
+	if columnOutputType&16 == 16 {
+		if err := os.MkdirAll(dirs.html, os.ModePerm); err != nil {
+			return dirs, err
+		}
+	}
+
+	return dirs, nil
 }
 
 func checkProductType(productType string) bool {
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/nicoxiang/geektime-downloader/internal/search"
+	"github.com/spf13/cobra"
+)
+
+var (
+	searchDir   string
+	searchLimit int
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search <查询>",
+	Short: "搜索已下载的文章, 支持短语 \"...\" 和字段 course: title: body: comments:",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := searchDir
+		if dir == "" {
+			dir = downloadFolder
+		}
+		dir, err := filepath.Abs(dir)
+		checkError(err)
+		if !search.Exists(dir) {
+			checkError(fmt.Errorf("搜索索引不存在, 请先执行 index 命令建立索引"))
+		}
+
+		hits, err := search.Search(dir, strings.Join(args, " "), searchLimit)
+		checkError(err)
+		if len(hits) == 0 {
+			fmt.Println("没有找到匹配的文章")
+			return
+		}
+		for i, h := range hits {
+			fmt.Printf("%d. [%s] %s (%.2f)\n", i+1, h.Course, h.Title, h.Score)
+			if h.Snippet != "" {
+				fmt.Printf("   %s\n", h.Snippet)
+			}
+			fmt.Printf("   %s\n\n", h.Path)
+		}
+	},
+}
+
+func init() {
+	searchCmd.Flags().StringVar(&searchDir, "dir", "", "下载目录, 默认使用程序的下载目录")
+	searchCmd.Flags().IntVar(&searchLimit, "limit", 20, "最多显示的结果数量")
+	rootCmd.AddCommand(searchCmd)
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/nicoxiang/geektime-downloader/internal/podcast"
+	"github.com/nicoxiang/geektime-downloader/internal/server"
+	"github.com/spf13/cobra"
+)
+
+var serveAddr string
+
+var serveCmd = &cobra.Command{
+	Use:   "serve [下载目录]",
+	Short: "启动本地 HTTP 服务, 在浏览器中浏览已下载的课程, 默认使用程序的下载目录",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := downloadFolder
+		if len(args) == 1 {
+			dir = args[0]
+		}
+		dir, err := filepath.Abs(dir)
+		checkError(err)
+
+		baseURL, err := podcast.BaseURL(serveAddr)
+		checkError(err)
+		fmt.Printf("课程浏览服务已启动: %s, 下载目录: %s, 按 Ctrl+C 退出\n", baseURL, dir)
+		checkError(server.Serve(cmd.Context(), dir, serveAddr))
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "127.0.0.1:8080", "课程浏览服务监听地址, 如需在局域网内访问可设置为 :8080")
+	rootCmd.AddCommand(serveCmd)
+}
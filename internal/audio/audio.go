@@ -7,27 +7,44 @@ import (
 
 	"github.com/nicoxiang/geektime-downloader/internal/geektime"
 	"github.com/nicoxiang/geektime-downloader/internal/pkg/downloader"
-	"github.com/nicoxiang/geektime-downloader/internal/pkg/filenamify"
 	"github.com/nicoxiang/geektime-downloader/internal/pkg/files"
 )
 
 const (
 	// MP3Extension ...
 	MP3Extension = ".mp3"
+	// NoAudioExtension is extension of marker file recording article without audio
+	NoAudioExtension = ".noaudio"
 )
 
-// DownloadAudio ...
-func DownloadAudio(ctx context.Context, downloadAudioURL, dir, title string, overwrite bool) (bool, error) {
+// Exists check whether audio of article was downloaded or recorded as missing before
+func Exists(dir, name string) bool {
+	return files.CheckFileExists(filepath.Join(dir, name+MP3Extension)) ||
+		files.CheckFileExists(filepath.Join(dir, name+NoAudioExtension))
+}
+
+// MarkNoAudio record article without audio, so later runs don't fetch its info again
+func MarkNoAudio(dir, name string) error {
+	dst := filepath.Join(dir, name+NoAudioExtension)
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, nil, 0644)
+}
+
+// DownloadAudio download mp3 as name in dir and write ID3v2 tags, name may contain sub dirs
+func DownloadAudio(ctx context.Context, downloadAudioURL, dir, name string, tags Tags, overwrite bool) (bool, error) {
 	if downloadAudioURL == "" {
 		return false, nil
 	}
-	filenamifyTitle := filenamify.Filenamify(title)
-
-	dst := filepath.Join(dir, filenamifyTitle+MP3Extension)
+	dst := filepath.Join(dir, name+MP3Extension)
 
 	if files.CheckFileExists(dst) && !overwrite {
 		return true, nil
 	}
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return false, err
+	}
 
 	headers := make(map[string]string, 2)
 	headers[geektime.Origin] = geektime.DefaultBaseURL
@@ -37,7 +54,11 @@ func DownloadAudio(ctx context.Context, downloadAudioURL, dir, title string, ove
 
 	if err != nil {
 		_ = os.Remove(dst)
+		return false, err
 	}
 
-	return false, err
+	if tags.Title == "" {
+		tags.Title = filepath.Base(name)
+	}
+	return false, WriteTags(ctx, dst, tags)
 }
@@ -0,0 +1,109 @@
+package audio
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bogem/id3v2/v2"
+)
+
+// File is a downloaded mp3 with its ID3 tags
+type File struct {
+	Path string
+	// Name is path relative to course dir without extension, same as markdown and pdf file name
+	Name    string
+	Title   string
+	Track   int
+	Album   string
+	Author  string
+	Size    int64
+	ModTime time.Time
+	// Summary is article summary saved in comment
+	Summary string
+}
+
+// ReadCourseDir list mp3 files in course audio dir and its sub dirs in course order, files
+// without track number are put at the end in file name order
+func ReadCourseDir(dir string) ([]File, error) {
+	var files []File
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(d.Name()), MP3Extension) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		f := File{
+			Path:    p,
+			Name:    strings.TrimSuffix(rel, filepath.Ext(rel)),
+			Title:   strings.TrimSuffix(d.Name(), filepath.Ext(d.Name())),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		}
+
+		tag, err := id3v2.Open(f.Path, id3v2.Options{Parse: true})
+		if err == nil {
+			if tag.Title() != "" {
+				f.Title = tag.Title()
+			}
+			track := tag.GetTextFrame(tag.CommonID("Track number/Position in set")).Text
+			f.Track, _ = strconv.Atoi(strings.Split(track, "/")[0])
+			f.Album = tag.Album()
+			f.Author = tag.GetTextFrame(tag.CommonID("Band/Orchestra/Accompaniment")).Text
+			if f.Author == "" {
+				f.Author = tag.Artist()
+			}
+			for _, frame := range tag.GetFrames(tag.CommonID("Comments")) {
+				if cf, ok := frame.(id3v2.CommentFrame); ok && cf.Text != "" {
+					f.Summary = cf.Text
+					break
+				}
+			}
+			_ = tag.Close()
+		}
+		files = append(files, f)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		ti, tj := files[i].Track, files[j].Track
+		if ti == 0 || tj == 0 {
+			return ti != 0 && tj == 0
+		}
+		return ti < tj
+	})
+	return files, nil
+}
+
+// ReadCover return the first embedded cover art and its mime type in files
+func ReadCover(files []File) ([]byte, string) {
+	for _, f := range files {
+		tag, err := id3v2.Open(f.Path, id3v2.Options{Parse: true})
+		if err != nil {
+			continue
+		}
+		for _, frame := range tag.GetFrames(tag.CommonID("Attached picture")) {
+			if pic, ok := frame.(id3v2.PictureFrame); ok && len(pic.Picture) > 0 {
+				_ = tag.Close()
+				return pic.Picture, pic.MimeType
+			}
+		}
+		_ = tag.Close()
+	}
+	return nil, ""
+}
@@ -0,0 +1,130 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/bogem/id3v2/v2"
+	"github.com/nicoxiang/geektime-downloader/internal/geektime"
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/logger"
+)
+
+// Tags is ID3v2 tags written to downloaded mp3
+type Tags struct {
+	Title  string
+	Album  string
+	Artist string
+	// AlbumArtist keeps all articles of one course grouped in players
+	AlbumArtist string
+	Track       int
+	TrackTotal  int
+	Year        int
+	CoverURL    string
+	// Summary of article is written as comment, used as episode description of podcast
+	Summary string
+}
+
+var (
+	// cover images cache, all articles in one course share the same cover
+	coverCache   = make(map[string][]byte)
+	coverCacheMu sync.Mutex
+)
+
+// WriteTags write ID3v2 tags and cover art into mp3 file
+func WriteTags(ctx context.Context, fileName string, tags Tags) error {
+	tag, err := id3v2.Open(fileName, id3v2.Options{Parse: true})
+	if err != nil {
+		return err
+	}
+	defer tag.Close()
+
+	// utf-8 text encoding is only supported in ID3v2.4
+	tag.SetVersion(4)
+	tag.SetDefaultEncoding(id3v2.EncodingUTF8)
+
+	if tags.Title != "" {
+		tag.SetTitle(tags.Title)
+	}
+	if tags.Album != "" {
+		tag.SetAlbum(tags.Album)
+	}
+	if tags.Artist != "" {
+		tag.SetArtist(tags.Artist)
+	}
+	if tags.AlbumArtist != "" {
+		tag.AddTextFrame(tag.CommonID("Band/Orchestra/Accompaniment"), id3v2.EncodingUTF8, tags.AlbumArtist)
+	}
+	if tags.Track > 0 {
+		track := strconv.Itoa(tags.Track)
+		if tags.TrackTotal > 0 {
+			track += "/" + strconv.Itoa(tags.TrackTotal)
+		}
+		tag.AddTextFrame(tag.CommonID("Track number/Position in set"), id3v2.EncodingUTF8, track)
+	}
+	if tags.Year > 0 {
+		tag.AddTextFrame(tag.CommonID("Recording time"), id3v2.EncodingUTF8, strconv.Itoa(tags.Year))
+	}
+
+	if tags.Summary != "" {
+		tag.DeleteFrames(tag.CommonID("Comments"))
+		tag.AddCommentFrame(id3v2.CommentFrame{
+			Encoding: id3v2.EncodingUTF8,
+			Language: "chi",
+			Text:     tags.Summary,
+		})
+	}
+
+	if tags.CoverURL != "" {
+		cover, err := fetchCover(ctx, tags.CoverURL)
+		if err != nil {
+			// cover art is nice to have, do not fail the whole download
+			logger.Warnf("Fetch audio cover %s failed: %v", tags.CoverURL, err)
+		} else {
+			tag.DeleteFrames(tag.CommonID("Attached picture"))
+			tag.AddAttachedPicture(id3v2.PictureFrame{
+				Encoding:    id3v2.EncodingUTF8,
+				MimeType:    http.DetectContentType(cover),
+				PictureType: id3v2.PTFrontCover,
+				Description: "Cover",
+				Picture:     cover,
+			})
+		}
+	}
+
+	return tag.Save()
+}
+
+func fetchCover(ctx context.Context, coverURL string) ([]byte, error) {
+	coverCacheMu.Lock()
+	defer coverCacheMu.Unlock()
+	if cover, ok := coverCache[coverURL]; ok {
+		return cover, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, coverURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(geektime.Origin, geektime.DefaultBaseURL)
+	req.Header.Set(geektime.UserAgent, geektime.DefaultUserAgent)
+
+	client := &http.Client{Timeout: geektime.DefaultTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	cover, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	coverCache[coverURL] = cover
+	return cover, nil
+}
@@ -0,0 +1,471 @@
+package audio
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/filenamify"
+)
+
+const (
+	// M4BExtension ...
+	M4BExtension = ".m4b"
+
+	// MPEG-1/2 audio layer 3 object type in esds
+	mp3ObjectType = 0x6B
+	// mvhd and tkhd time unit is millisecond
+	movieTimescale = 1000
+	audioTrackID   = 1
+	chapterTrackID = 2
+	// nero chapter count is stored in one byte
+	maxNeroChapters = 255
+)
+
+// ErrNoAudioToMerge ...
+var ErrNoAudioToMerge = errors.New("没有可合并的音频文件")
+
+// AudiobookInfo is metadata of audiobook
+type AudiobookInfo struct {
+	Title     string
+	Author    string
+	Cover     []byte
+	CoverMIME string
+}
+
+// MergeCourseAudio write all mp3 in course audio dir into <course>.m4b next to dir,
+// title and author are taken from ID3 tags if empty
+func MergeCourseAudio(ctx context.Context, dir, title, author string) (string, error) {
+	files, err := ReadCourseDir(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", ErrNoAudioToMerge
+	}
+	for _, f := range files {
+		if title == "" {
+			title = f.Album
+		}
+		if author == "" {
+			author = f.Author
+		}
+	}
+	if title == "" {
+		title = filepath.Base(dir)
+	}
+
+	info := AudiobookInfo{Title: title, Author: author}
+	info.Cover, info.CoverMIME = ReadCover(files)
+
+	out := filepath.Join(filepath.Dir(dir), filenamify.Filenamify(title)+M4BExtension)
+	return out, WriteAudiobook(ctx, files, out, info)
+}
+
+type chapter struct {
+	title string
+	// duration in audio samples
+	duration uint64
+}
+
+// WriteAudiobook concatenates mp3 files in order into one MP4 audio container (.m4b),
+// with a chapter at each file boundary named by file title and embedded cover.
+// MP3 frames are stored as is, no transcoding and no ffmpeg needed.
+func WriteAudiobook(ctx context.Context, files []File, out string, info AudiobookInfo) error {
+	if len(files) == 0 {
+		return ErrNoAudioToMerge
+	}
+
+	streams := make([]*mp3Stream, len(files))
+	for i, f := range files {
+		s, err := readMP3Stream(f.Path)
+		if err != nil {
+			return err
+		}
+		if i > 0 && (s.sampleRate != streams[0].sampleRate || s.samplesPerFrame != streams[0].samplesPerFrame) {
+			return fmt.Errorf("音频 %s 的采样率与其他音频不一致, 无法合并", f.Path)
+		}
+		streams[i] = s
+	}
+	sampleRate := streams[0].sampleRate
+	samplesPerFrame := streams[0].samplesPerFrame
+
+	ftyp := mp4Box("ftyp", []byte("M4B "), u32(0), []byte("M4B M4A mp42isom"))
+	// mdat use 64 bit large size
+	dataStart := uint64(len(ftyp)) + 16
+
+	var chapters []chapter
+	var audioSize uint64
+	var chunkOffsets []uint64
+	for i, s := range streams {
+		chunkOffsets = append(chunkOffsets, dataStart+audioSize)
+		for _, size := range s.sizes {
+			audioSize += uint64(size)
+		}
+		chapters = append(chapters, chapter{
+			title:    files[i].Title,
+			duration: uint64(len(s.sizes) * samplesPerFrame),
+		})
+	}
+
+	var chapterSamples [][]byte
+	var chapterOffsets []uint64
+	offset := dataStart + audioSize
+	for _, c := range chapters {
+		sample := textSample(c.title)
+		chapterSamples = append(chapterSamples, sample)
+		chapterOffsets = append(chapterOffsets, offset)
+		offset += uint64(len(sample))
+	}
+	mdatSize := offset - uint64(len(ftyp))
+
+	moov := buildMoov(streams, chapters, chunkOffsets, chapterOffsets, sampleRate, audioSize, info)
+
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriterSize(f, 256*1024)
+	err = func() error {
+		if _, err := w.Write(ftyp); err != nil {
+			return err
+		}
+		if _, err := w.Write(append(append(u32(1), "mdat"...), u64(mdatSize)...)); err != nil {
+			return err
+		}
+		for i, s := range streams {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := copyFrames(w, files[i].Path, s); err != nil {
+				return err
+			}
+		}
+		for _, sample := range chapterSamples {
+			if _, err := w.Write(sample); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(moov); err != nil {
+			return err
+		}
+		return w.Flush()
+	}()
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(out)
+	}
+	return err
+}
+
+func copyFrames(w io.Writer, fileName string, s *mp3Stream) error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var size int64
+	for _, v := range s.sizes {
+		size += int64(v)
+	}
+	_, err = io.Copy(w, io.NewSectionReader(f, s.offset, size))
+	return err
+}
+
+func buildMoov(streams []*mp3Stream,
+	chapters []chapter,
+	chunkOffsets, chapterOffsets []uint64,
+	sampleRate int,
+	audioSize uint64,
+	info AudiobookInfo,
+) []byte {
+	var totalSamples uint64
+	var frameSizes []uint32
+	channels := 1
+	for _, s := range streams {
+		frameSizes = append(frameSizes, s.sizes...)
+		if s.channels > channels {
+			channels = s.channels
+		}
+	}
+	samplesPerFrame := streams[0].samplesPerFrame
+	totalSamples = uint64(len(frameSizes) * samplesPerFrame)
+	movieDuration := totalSamples * movieTimescale / uint64(sampleRate)
+
+	// audio track, one chunk per mp3 file
+	var stsc [][]byte
+	for i, s := range streams {
+		if i == 0 || len(s.sizes) != len(streams[i-1].sizes) {
+			stsc = append(stsc, u32(uint32(i+1)), u32(uint32(len(s.sizes))), u32(1))
+		}
+	}
+	stsz := [][]byte{u32(0), u32(uint32(len(frameSizes)))}
+	for _, size := range frameSizes {
+		stsz = append(stsz, u32(size))
+	}
+	avgBitrate := uint32(0)
+	if totalSamples > 0 {
+		avgBitrate = uint32(audioSize * 8 * uint64(sampleRate) / totalSamples)
+	}
+	audioEntry := mp4Box("mp4a",
+		make([]byte, 6), u16(1), // reserved, data reference index
+		make([]byte, 8), // version, revision, vendor
+		u16(uint16(channels)), u16(16), u16(0), u16(0),
+		u32(uint32(sampleRate)<<16),
+		esds(avgBitrate),
+	)
+	audioTrak := mp4Box("trak",
+		tkhd(audioTrackID, 3, movieDuration, 0x0100),
+		mp4Box("tref", mp4Box("chap", u32(chapterTrackID))),
+		mp4Box("mdia",
+			mdhd(sampleRate, totalSamples),
+			hdlr("soun", "SoundHandler"),
+			mp4Box("minf",
+				fullBox("smhd", 0, 0, u16(0), u16(0)),
+				dinf(),
+				mp4Box("stbl",
+					fullBox("stsd", 0, 0, u32(1), audioEntry),
+					fullBox("stts", 0, 0, u32(1), u32(uint32(len(frameSizes))), u32(uint32(samplesPerFrame))),
+					fullBox("stsc", 0, 0, append([][]byte{u32(uint32(len(stsc) / 3))}, stsc...)...),
+					fullBox("stsz", 0, 0, stsz...),
+					co64(chunkOffsets),
+				),
+			),
+		),
+	)
+
+	// quicktime chapter track, one text sample per chapter
+	stts := [][]byte{u32(uint32(len(chapters)))}
+	for _, c := range chapters {
+		stts = append(stts, u32(1), u32(uint32(c.duration)))
+	}
+	chapterSizes := [][]byte{u32(0), u32(uint32(len(chapters)))}
+	for _, c := range chapters {
+		chapterSizes = append(chapterSizes, u32(uint32(len(textSample(c.title)))))
+	}
+	chapterTrak := mp4Box("trak",
+		tkhd(chapterTrackID, 0, movieDuration, 0),
+		mp4Box("mdia",
+			mdhd(sampleRate, totalSamples),
+			hdlr("text", "ChapterHandler"),
+			mp4Box("minf",
+				gmhd(),
+				dinf(),
+				mp4Box("stbl",
+					fullBox("stsd", 0, 0, u32(1), mp4Box("text", make([]byte, 6), u16(1), textSampleEntry)),
+					fullBox("stts", 0, 0, stts...),
+					fullBox("stsc", 0, 0, u32(1), u32(1), u32(1), u32(1)),
+					fullBox("stsz", 0, 0, chapterSizes...),
+					co64(chapterOffsets),
+				),
+			),
+		),
+	)
+
+	return mp4Box("moov",
+		mvhd(movieDuration),
+		audioTrak,
+		chapterTrak,
+		mp4Box("udta", chpl(chapters, sampleRate), itunesMeta(info)),
+	)
+}
+
+func mvhd(duration uint64) []byte {
+	return fullBox("mvhd", 0, 0,
+		u32(0), u32(0), // creation and modification time
+		u32(movieTimescale), u32(uint32(duration)),
+		u32(0x00010000), u16(0x0100), // rate, volume
+		make([]byte, 10),
+		matrix(),
+		make([]byte, 24),
+		u32(chapterTrackID+1),
+	)
+}
+
+func tkhd(trackID uint32, flags uint32, duration uint64, volume uint16) []byte {
+	return fullBox("tkhd", 0, flags,
+		u32(0), u32(0),
+		u32(trackID), u32(0),
+		u32(uint32(duration)),
+		make([]byte, 8),
+		u16(0), u16(0), // layer, alternate group
+		u16(volume), u16(0),
+		matrix(),
+		u32(0), u32(0), // width, height
+	)
+}
+
+func mdhd(timescale int, duration uint64) []byte {
+	return fullBox("mdhd", 1, 0,
+		u64(0), u64(0),
+		u32(uint32(timescale)), u64(duration),
+		u16(0x55C4), u16(0), // language "und"
+	)
+}
+
+func hdlr(handlerType, name string) []byte {
+	return fullBox("hdlr", 0, 0, u32(0), []byte(handlerType), make([]byte, 12), []byte(name+"\x00"))
+}
+
+func dinf() []byte {
+	return mp4Box("dinf", fullBox("dref", 0, 0, u32(1), fullBox("url ", 0, 1)))
+}
+
+// gmhd is base media header of quicktime text track
+func gmhd() []byte {
+	return mp4Box("gmhd",
+		fullBox("gmin", 0, 0, u16(0x40), u16(0x8000), u16(0x8000), u16(0x8000), u16(0), u16(0)),
+		mp4Box("text",
+			u16(1), u32(0), u32(0), u32(0),
+			u32(1), u32(0), u32(0), u32(0),
+			u32(0x00004000), u16(0),
+		),
+	)
+}
+
+func co64(offsets []uint64) []byte {
+	payload := [][]byte{u32(uint32(len(offsets)))}
+	for _, o := range offsets {
+		payload = append(payload, u64(o))
+	}
+	return fullBox("co64", 0, 0, payload...)
+}
+
+func esds(avgBitrate uint32) []byte {
+	decoderConfig := descriptor(0x04,
+		[]byte{mp3ObjectType, 0x15}, // audio stream
+		[]byte{0, 0, 0},             // buffer size
+		u32(avgBitrate), u32(avgBitrate),
+	)
+	slConfig := descriptor(0x06, []byte{0x02})
+	return fullBox("esds", 0, 0, descriptor(0x03, u16(audioTrackID), []byte{0}, decoderConfig, slConfig))
+}
+
+func descriptor(tag byte, payload ...[]byte) []byte {
+	data := concat(payload...)
+	// 4 bytes length
+	n := len(data)
+	return concat([]byte{tag, byte(n>>21&0x7F | 0x80), byte(n>>14&0x7F | 0x80), byte(n>>7&0x7F | 0x80), byte(n & 0x7F)}, data)
+}
+
+// chpl is nero chapter list, start time is in 100 nanoseconds
+func chpl(chapters []chapter, sampleRate int) []byte {
+	if len(chapters) > maxNeroChapters {
+		chapters = chapters[:maxNeroChapters]
+	}
+	payload := [][]byte{u32(0), {byte(len(chapters))}}
+	var start uint64
+	for _, c := range chapters {
+		title := truncateUTF8(c.title, 255)
+		payload = append(payload, u64(start*10000000/uint64(sampleRate)), []byte{byte(len(title))}, []byte(title))
+		start += c.duration
+	}
+	return fullBox("chpl", 1, 0, payload...)
+}
+
+func itunesMeta(info AudiobookInfo) []byte {
+	var items [][]byte
+	if info.Title != "" {
+		items = append(items, mp4Box("\xa9nam", dataBox(1, []byte(info.Title))))
+		items = append(items, mp4Box("\xa9alb", dataBox(1, []byte(info.Title))))
+	}
+	if info.Author != "" {
+		items = append(items, mp4Box("\xa9ART", dataBox(1, []byte(info.Author))))
+	}
+	items = append(items, mp4Box("\xa9gen", dataBox(1, []byte("Audiobook"))))
+	// media kind audiobook
+	items = append(items, mp4Box("stik", dataBox(21, []byte{2})))
+	if len(info.Cover) > 0 {
+		coverType := uint32(13)
+		if strings.Contains(info.CoverMIME, "png") {
+			coverType = 14
+		}
+		items = append(items, mp4Box("covr", dataBox(coverType, info.Cover)))
+	}
+	return fullBox("meta", 0, 0,
+		fullBox("hdlr", 0, 0, u32(0), []byte("mdirappl"), u32(0), u32(0), []byte{0}),
+		mp4Box("ilst", items...),
+	)
+}
+
+func dataBox(dataType uint32, value []byte) []byte {
+	return mp4Box("data", u32(dataType), u32(0), value)
+}
+
+// text sample entry used by quicktime chapter track, same as ffmpeg
+var textSampleEntry = []byte{
+	0x00, 0x00, 0x00, 0x01, // display flags
+	0x00, 0x00, // justification
+	0x00, 0x00, 0x00, 0x00, // background color
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // default text box
+	0x00, 0x00, 0x00, 0x00, // start char, end char
+	0x00, 0x01, // font id
+	0x00, 0x00, // font style flags, font size
+	0x00, 0x00, 0x00, 0x00, // foreground color
+	0x00, 0x00, 0x00, 0x0D, 'f', 't', 'a', 'b', 0x00, 0x01, 0x00, 0x01, 0x00, // font table
+}
+
+func textSample(title string) []byte {
+	title = truncateUTF8(title, 0xFFFF)
+	return concat(u16(uint16(len(title))), []byte(title), mp4Box("encd", u32(0x00000100)))
+}
+
+func truncateUTF8(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	// cut at the last rune boundary not after maxBytes
+	end := 0
+	for i := range s {
+		if i > maxBytes {
+			break
+		}
+		end = i
+	}
+	return s[:end]
+}
+
+func matrix() []byte {
+	return concat(u32(0x00010000), u32(0), u32(0), u32(0), u32(0x00010000), u32(0), u32(0), u32(0), u32(0x40000000))
+}
+
+func mp4Box(typ string, payload ...[]byte) []byte {
+	data := concat(payload...)
+	return concat(u32(uint32(8+len(data))), []byte(typ), data)
+}
+
+func fullBox(typ string, version byte, flags uint32, payload ...[]byte) []byte {
+	return mp4Box(typ, append([][]byte{{version, byte(flags >> 16), byte(flags >> 8), byte(flags)}}, payload...)...)
+}
+
+func concat(bs ...[]byte) []byte {
+	n := 0
+	for _, b := range bs {
+		n += len(b)
+	}
+	data := make([]byte, 0, n)
+	for _, b := range bs {
+		data = append(data, b...)
+	}
+	return data
+}
+
+func u16(v uint16) []byte {
+	return binary.BigEndian.AppendUint16(nil, v)
+}
+
+func u32(v uint32) []byte {
+	return binary.BigEndian.AppendUint32(nil, v)
+}
+
+func u64(v uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, v)
+}
@@ -0,0 +1,239 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	// MPEG-1 layer 3, 128 kbps, 44100 Hz, stereo
+	testFrameSize  = 417
+	testSampleRate = 44100
+)
+
+// writeTestMP3 write mp3 with ID3v2 tag, Info header frame, n silent frames and ID3v1 tag
+func writeTestMP3(t *testing.T, name string, n int) string {
+	t.Helper()
+	frame := func() []byte {
+		b := make([]byte, testFrameSize)
+		copy(b, []byte{0xFF, 0xFB, 0x90, 0x00})
+		return b
+	}
+	var buf bytes.Buffer
+	buf.Write([]byte{'I', 'D', '3', 3, 0, 0, 0, 0, 0, 0})
+	info := frame()
+	copy(info[36:], "Info")
+	buf.Write(info)
+	for i := 0; i < n; i++ {
+		buf.Write(frame())
+	}
+	buf.Write(append([]byte("TAG"), make([]byte, 125)...))
+
+	p := filepath.Join(t.TempDir(), name+MP3Extension)
+	if err := os.WriteFile(p, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestReadMP3Stream(t *testing.T) {
+	s, err := readMP3Stream(writeTestMP3(t, "a", 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.offset != 10+testFrameSize || len(s.sizes) != 3 || s.sizes[0] != testFrameSize {
+		t.Errorf("offset = %d, frames = %v", s.offset, s.sizes)
+	}
+	if s.sampleRate != testSampleRate || s.channels != 2 || s.samplesPerFrame != 1152 {
+		t.Errorf("stream = %+v", s)
+	}
+}
+
+func TestIsVBRHeaderFrame(t *testing.T) {
+	for _, c := range []struct {
+		header []byte
+		offset int
+		tag    string
+		want   bool
+	}{
+		// MPEG-1 stereo and mono, MPEG-2 stereo and mono
+		{[]byte{0xFF, 0xFB, 0x90, 0x00}, 36, "Xing", true},
+		{[]byte{0xFF, 0xFB, 0x90, 0xC0}, 21, "Info", true},
+		{[]byte{0xFF, 0xF3, 0x90, 0x00}, 21, "Xing", true},
+		{[]byte{0xFF, 0xF3, 0x90, 0xC0}, 13, "Info", true},
+		{[]byte{0xFF, 0xF3, 0x90, 0xC0}, 36, "VBRI", true},
+		// tag at wrong offset is audio data
+		{[]byte{0xFF, 0xFB, 0x90, 0x00}, 21, "Info", false},
+		{[]byte{0xFF, 0xFB, 0x90, 0xC0}, 36, "Xing", false},
+		{[]byte{0xFF, 0xFB, 0x90, 0x00}, 40, "VBRI", false},
+	} {
+		h, ok := parseMP3FrameHeader(c.header)
+		if !ok {
+			t.Fatalf("invalid header % x", c.header)
+		}
+		frame := make([]byte, h.size)
+		copy(frame, c.header)
+		copy(frame[c.offset:], c.tag)
+		if got := isVBRHeaderFrame(frame, h); got != c.want {
+			t.Errorf("%s at %d of header % x = %t, want %t", c.tag, c.offset, c.header, got, c.want)
+		}
+	}
+}
+
+type box struct {
+	typ  string
+	data []byte
+	// offset of box in file
+	offset int
+}
+
+// parseBoxes split data into boxes, base is offset of data in file
+func parseBoxes(t *testing.T, data []byte, base int) []box {
+	t.Helper()
+	var boxes []box
+	for i := 0; i < len(data); {
+		if len(data)-i < 8 {
+			t.Fatalf("truncated box at %d", base+i)
+		}
+		size := int(binary.BigEndian.Uint32(data[i:]))
+		header := 8
+		if size == 1 {
+			size = int(binary.BigEndian.Uint64(data[i+8:]))
+			header = 16
+		}
+		if size < header || i+size > len(data) {
+			t.Fatalf("invalid box size %d at %d", size, base+i)
+		}
+		boxes = append(boxes, box{typ: string(data[i+4 : i+8]), data: data[i+header : i+size], offset: base + i})
+		i += size
+	}
+	return boxes
+}
+
+// child find box by path, skip is bytes before child boxes in payload of b, like entry count of stsd
+func child(t *testing.T, b box, skip int, path ...string) box {
+	t.Helper()
+	for _, typ := range path {
+		found := false
+		for _, c := range parseBoxes(t, b.data[skip:], b.offset) {
+			if c.typ == typ {
+				b, found = c, true
+				break
+			}
+		}
+		if !found {
+			t.Fatalf("box %s not found", typ)
+		}
+		skip = 0
+	}
+	return b
+}
+
+func TestWriteAudiobook(t *testing.T) {
+	files := []File{
+		{Path: writeTestMP3(t, "01", 3), Title: "开篇词"},
+		{Path: writeTestMP3(t, "02", 5), Title: "第一讲"},
+	}
+	out := filepath.Join(t.TempDir(), "course"+M4BExtension)
+	if err := WriteAudiobook(context.Background(), files, out, AudiobookInfo{Title: "课程", Author: "作者"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	top := parseBoxes(t, data, 0)
+	if len(top) != 3 || top[0].typ != "ftyp" || top[1].typ != "mdat" || top[2].typ != "moov" {
+		t.Fatalf("top level boxes = %v", boxTypes(top))
+	}
+	var traks []box
+	for _, b := range parseBoxes(t, top[2].data, top[2].offset) {
+		if b.typ == "trak" {
+			traks = append(traks, b)
+		}
+	}
+	if len(traks) != 2 {
+		t.Fatalf("got %d tracks, want audio and chapter track", len(traks))
+	}
+
+	// audio track
+	stbl := child(t, traks[0], 0, "mdia", "minf", "stbl")
+	mp4a := child(t, child(t, stbl, 0, "stsd"), 8, "mp4a")
+	esds := child(t, mp4a, 28, "esds")
+	if !bytes.Contains(esds.data, []byte{0x04, 0x80, 0x80, 0x80, 0x0D, mp3ObjectType}) {
+		t.Error("esds object type is not mp3")
+	}
+	if n := binary.BigEndian.Uint32(child(t, stbl, 0, "stsz").data[8:]); n != 8 {
+		t.Errorf("audio sample count = %d, want 8", n)
+	}
+	offsets := chunkOffsets(child(t, stbl, 0, "co64"))
+	if len(offsets) != 2 || offsets[1]-offsets[0] != 3*testFrameSize {
+		t.Errorf("audio chunk offsets = %v", offsets)
+	}
+	for _, o := range offsets {
+		if !bytes.HasPrefix(data[o:], []byte{0xFF, 0xFB, 0x90, 0x00}) {
+			t.Errorf("chunk at %d is not mp3 frame", o)
+		}
+	}
+
+	// nero chapters
+	chpl := child(t, top[2], 0, "udta", "chpl").data
+	if chpl[8] != 2 {
+		t.Fatalf("chpl count = %d, want 2", chpl[8])
+	}
+	second := 9 + 8 + 1 + len("开篇词")
+	if start := binary.BigEndian.Uint64(chpl[second:]); start != 3*1152*10000000/testSampleRate {
+		t.Errorf("second chapter start = %d", start)
+	}
+	if title := string(chpl[second+9 : second+9+int(chpl[second+8])]); title != "第一讲" {
+		t.Errorf("second chapter title = %q", title)
+	}
+
+	// quicktime chapter track referenced by audio track
+	if id := binary.BigEndian.Uint32(child(t, traks[0], 0, "tref", "chap").data); id != chapterTrackID {
+		t.Errorf("chapter track reference = %d", id)
+	}
+	chapterStbl := child(t, traks[1], 0, "mdia", "minf", "stbl")
+	for i, o := range chunkOffsets(child(t, chapterStbl, 0, "co64")) {
+		n := int(binary.BigEndian.Uint16(data[o:]))
+		if title := string(data[o+2 : o+2+uint64(n)]); title != files[i].Title {
+			t.Errorf("chapter sample %d = %q, want %q", i, title, files[i].Title)
+		}
+	}
+}
+
+func chunkOffsets(co64 box) []uint64 {
+	n := binary.BigEndian.Uint32(co64.data[4:])
+	var offsets []uint64
+	for i := 0; i < int(n); i++ {
+		offsets = append(offsets, binary.BigEndian.Uint64(co64.data[8+8*i:]))
+	}
+	return offsets
+}
+
+func boxTypes(boxes []box) []string {
+	var types []string
+	for _, b := range boxes {
+		types = append(types, b.typ)
+	}
+	return types
+}
+
+func TestReadCourseDirSummary(t *testing.T) {
+	fileName := writeTestMP3(t, "a", 1)
+	if err := WriteTags(context.Background(), fileName, Tags{Title: "标题", Track: 1, Summary: "文章摘要"}); err != nil {
+		t.Fatal(err)
+	}
+	files, err := ReadCourseDir(filepath.Dir(fileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Title != "标题" || files[0].Summary != "文章摘要" {
+		t.Errorf("files = %+v", files)
+	}
+}
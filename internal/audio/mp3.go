@@ -0,0 +1,184 @@
+package audio
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrInvalidMP3 ...
+var ErrInvalidMP3 = errors.New("不是有效的 MP3 文件")
+
+var (
+	// layer 3 bitrates in kbps, index 0 is free format and not supported
+	mpeg1Bitrates = [16]int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0}
+	mpeg2Bitrates = [16]int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0}
+	sampleRates   = map[int][3]int{
+		1:  {44100, 48000, 32000},
+		2:  {22050, 24000, 16000},
+		25: {11025, 12000, 8000},
+	}
+)
+
+// mp3Stream is frames layout of one mp3 file
+type mp3Stream struct {
+	sampleRate      int
+	channels        int
+	samplesPerFrame int
+	// offset of first frame and size of every frame, frames are contiguous
+	offset int64
+	sizes  []uint32
+}
+
+type mp3FrameHeader struct {
+	version         int
+	sampleRate      int
+	channels        int
+	samplesPerFrame int
+	size            int
+}
+
+func parseMP3FrameHeader(b []byte) (mp3FrameHeader, bool) {
+	var h mp3FrameHeader
+	if len(b) < 4 || b[0] != 0xFF || b[1]&0xE0 != 0xE0 {
+		return h, false
+	}
+	switch (b[1] >> 3) & 0x03 {
+	case 0:
+		h.version = 25
+	case 2:
+		h.version = 2
+	case 3:
+		h.version = 1
+	default:
+		return h, false
+	}
+	// layer 3 only
+	if (b[1]>>1)&0x03 != 1 {
+		return h, false
+	}
+	bitrateIndex := b[2] >> 4
+	sampleRateIndex := (b[2] >> 2) & 0x03
+	if sampleRateIndex == 3 {
+		return h, false
+	}
+	padding := int((b[2] >> 1) & 0x01)
+	bitrate := mpeg1Bitrates[bitrateIndex]
+	if h.version != 1 {
+		bitrate = mpeg2Bitrates[bitrateIndex]
+	}
+	if bitrate == 0 {
+		return h, false
+	}
+	h.sampleRate = sampleRates[h.version][sampleRateIndex]
+	h.channels = 2
+	if b[3]>>6 == 3 {
+		h.channels = 1
+	}
+	if h.version == 1 {
+		h.samplesPerFrame = 1152
+		h.size = 144*bitrate*1000/h.sampleRate + padding
+	} else {
+		h.samplesPerFrame = 576
+		h.size = 72*bitrate*1000/h.sampleRate + padding
+	}
+	return h, true
+}
+
+// readMP3Stream scan all frames of mp3 file, ID3v2 tag, Xing/Info header frame and
+// trailing ID3v1 tag are skipped
+func readMP3Stream(fileName string) (*mp3Stream, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReaderSize(f, 64*1024)
+
+	var offset int64
+	header, err := r.Peek(10)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidMP3, fileName)
+	}
+	if string(header[:3]) == "ID3" {
+		// syncsafe integer
+		size := 10 + (int64(header[6])<<21 | int64(header[7])<<14 | int64(header[8])<<7 | int64(header[9]))
+		if header[5]&0x10 != 0 {
+			// footer present
+			size += 10
+		}
+		if _, err := r.Discard(int(size)); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidMP3, fileName)
+		}
+		offset = size
+	}
+
+	s := &mp3Stream{}
+	frame := make([]byte, 0, 4096)
+	for {
+		b, err := r.Peek(4)
+		if err != nil {
+			break
+		}
+		h, ok := parseMP3FrameHeader(b)
+		if !ok {
+			if len(s.sizes) == 0 {
+				// garbage before first frame
+				_, _ = r.Discard(1)
+				offset++
+				continue
+			}
+			// ID3v1 tag or garbage at end of file
+			break
+		}
+		frame = frame[:h.size]
+		if _, err := io.ReadFull(r, frame); err != nil {
+			// truncated last frame
+			break
+		}
+
+		if len(s.sizes) == 0 {
+			if s.sampleRate == 0 && isVBRHeaderFrame(frame, h) {
+				offset += int64(h.size)
+				s.sampleRate = h.sampleRate
+				continue
+			}
+			s.offset = offset
+			s.sampleRate = h.sampleRate
+			s.channels = h.channels
+			s.samplesPerFrame = h.samplesPerFrame
+		} else if h.sampleRate != s.sampleRate {
+			return nil, fmt.Errorf("%w: %s 采样率不一致", ErrInvalidMP3, fileName)
+		}
+		s.sizes = append(s.sizes, uint32(h.size))
+		offset += int64(h.size)
+	}
+
+	if len(s.sizes) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidMP3, fileName)
+	}
+	return s, nil
+}
+
+// isVBRHeaderFrame check Xing or Info tag right after side info, or VBRI tag 32 bytes after
+// frame header, such first frame contains no audio
+func isVBRHeaderFrame(frame []byte, h mp3FrameHeader) bool {
+	// side info size depends on version and channel mode
+	var sideInfo int
+	switch {
+	case h.version == 1 && h.channels == 2:
+		sideInfo = 32
+	case h.version == 1, h.channels == 2:
+		sideInfo = 17
+	default:
+		sideInfo = 9
+	}
+	xing := 4 + sideInfo
+	return hasTagAt(frame, xing, "Xing") || hasTagAt(frame, xing, "Info") || hasTagAt(frame, 4+32, "VBRI")
+}
+
+func hasTagAt(frame []byte, offset int, tag string) bool {
+	return len(frame) >= offset+len(tag) && string(frame[offset:offset+len(tag)]) == tag
+}
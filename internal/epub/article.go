@@ -0,0 +1,99 @@
+package epub
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/nicoxiang/geektime-downloader/internal/geektime"
+	"github.com/nicoxiang/geektime-downloader/internal/links"
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/files"
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/htmlutil"
+)
+
+const (
+	// XHTMLExtension ...
+	XHTMLExtension = ".xhtml"
+
+	xhtmlHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="zh-CN" lang="zh-CN">
+<head>
+<meta charset="UTF-8"/>
+<title>`
+	xhtmlHeaderEnd = `</title>
+<link rel="stylesheet" type="text/css" href="style.css"/>
+</head>
+<body>
+`
+	xhtmlFooter = `
+</body>
+</html>
+`
+)
+
+// ArticleFileName xhtml file name of article in epub work dir
+func ArticleFileName(aid int) string {
+	return "article-" + strconv.Itoa(aid) + XHTMLExtension
+}
+
+// CommentsFileName xhtml file name of article comments in epub work dir
+func CommentsFileName(aid int) string {
+	return "comments-" + strconv.Itoa(aid) + XHTMLExtension
+}
+
+// SaveArticle convert article html to xhtml chapter in epub work dir, images are downloaded to
+// images shared by chapters. Comments are written to a separate appendix page if not empty.
+// Links to articles of the same course point to their chapters.
+func SaveArticle(ctx context.Context,
+	dir,
+	title string,
+	aid int,
+	articleHTML string,
+	comments []geektime.Comment,
+	resolver *links.Resolver,
+	overwrite bool,
+) (bool, error) {
+	fileName := filepath.Join(dir, ArticleFileName(aid))
+	if files.CheckFileExists(fileName) && !overwrite {
+		return true, nil
+	}
+
+	body, err := htmlutil.ParseBody(articleHTML)
+	if err != nil {
+		return false, err
+	}
+	htmlutil.AbsolutizeLinks(body)
+	htmlutil.RewriteLinks(body, func(href string) (string, bool) {
+		if _, sameCourse, _ := resolver.Resolve(title, href); sameCourse {
+			target, _ := links.ArticleID(href)
+			return ArticleFileName(target), true
+		}
+		return "", false
+	})
+	if err := htmlutil.LocalizeImages(ctx, body, dir); err != nil {
+		return false, err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(xhtmlHeader + escapeXML(title) + xhtmlHeaderEnd)
+	sb.WriteString(`<section epub:type="chapter">` + "\n<h1>" + escapeXML(title) + "</h1>\n")
+	writeChildrenXHTML(&sb, body)
+	sb.WriteString("\n</section>" + xhtmlFooter)
+
+	if len(comments) > 0 {
+		if err := os.WriteFile(filepath.Join(dir, CommentsFileName(aid)), []byte(commentsXHTML(title, comments)), 0644); err != nil {
+			return false, err
+		}
+	}
+	return false, os.WriteFile(fileName, []byte(sb.String()), 0644)
+}
+
+func commentsXHTML(title string, comments []geektime.Comment) string {
+	return xhtmlHeader + "精选留言 - " + escapeXML(title) + xhtmlHeaderEnd +
+		`<section epub:type="appendix" class="comments">` + "\n<h2>" + escapeXML(title) + "</h2>\n" +
+		htmlutil.CommentsHTML(comments) +
+		"</section>" + xhtmlFooter
+}
@@ -0,0 +1,470 @@
+package epub
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nicoxiang/geektime-downloader/internal/geektime"
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/downloader"
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/filenamify"
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/files"
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/logger"
+)
+
+const (
+	// EPUBExtension ...
+	EPUBExtension = ".epub"
+
+	mimeType = "application/epub+zip"
+
+	// content documents are stored in OEBPS folder of epub container
+	contentDir = "OEBPS"
+	opfName    = "content.opf"
+	navName    = "nav.xhtml"
+	ncxName    = "toc.ncx"
+	coverName  = "cover.xhtml"
+	styleName  = "style.css"
+
+	containerXML = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="` + contentDir + "/" + opfName + `" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+	styleCSS = `body { font-family: serif; line-height: 1.6; margin: 0 0.5em; }
+h1, h2, h3, h4 { font-family: sans-serif; line-height: 1.3; }
+img { max-width: 100%; height: auto; }
+pre { white-space: pre-wrap; word-wrap: break-word; background: #f6f8fa; padding: 0.5em; font-size: 0.85em; }
+code { font-family: monospace; }
+blockquote { margin: 0.5em 0 0.5em 1em; padding-left: 0.5em; border-left: 3px solid #ccc; color: #555; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 0.2em 0.4em; }
+.cover { text-align: center; margin: 0; padding: 0; }
+.cover img { max-height: 100%; }
+.comment { margin-bottom: 1em; }
+.comment-meta { color: #666; font-size: 0.9em; margin-bottom: 0; }
+`
+)
+
+// ErrNoArticle ...
+var ErrNoArticle = errors.New("没有可打包为 EPUB 的文章")
+
+type manifestItem struct {
+	id         string
+	href       string
+	mediaType  string
+	properties string
+}
+
+type navEntry struct {
+	title    string
+	href     string
+	children []*navEntry
+}
+
+var chapterLinkRegexp = regexp.MustCompile(`href="article-(\d+)\.xhtml(?:#[^"]*)?"`)
+
+// Build package xhtml chapters in epub work dir into <course>.epub next to dir,
+// with cover, nav document and ncx grouped by chapter, and comments appendix if exists.
+// The result is validated before returned.
+func Build(ctx context.Context, course geektime.Course, dir string) (string, error) {
+	var articles []geektime.Article
+	for _, a := range course.Articles {
+		if files.CheckFileExists(filepath.Join(dir, ArticleFileName(a.AID))) {
+			articles = append(articles, a)
+		} else {
+			logger.Warnf("Build epub skip article %s, file not exists", a.Title)
+		}
+	}
+	if len(articles) == 0 {
+		return "", ErrNoArticle
+	}
+
+	generated := make(map[string]string)
+	if err := unlinkMissingChapters(dir, articles, generated); err != nil {
+		return "", err
+	}
+
+	coverImage := downloadCover(ctx, course.Cover, dir)
+
+	var manifest []manifestItem
+	var spine []string
+	if coverImage != "" {
+		manifest = append(manifest,
+			manifestItem{id: "cover-image", href: coverImage, mediaType: mediaTypeOfFile(filepath.Join(dir, coverImage)), properties: "cover-image"},
+			manifestItem{id: "cover", href: coverName, mediaType: "application/xhtml+xml"},
+		)
+		spine = append(spine, "cover")
+	}
+	manifest = append(manifest,
+		manifestItem{id: "nav", href: navName, mediaType: "application/xhtml+xml", properties: "nav"},
+		manifestItem{id: "ncx", href: ncxName, mediaType: "application/x-dtbncx+xml"},
+		manifestItem{id: "style", href: styleName, mediaType: "text/css"},
+	)
+	spine = append(spine, "nav")
+
+	// toc grouped by section title
+	var toc []*navEntry
+	for _, a := range articles {
+		id := "article-" + strconv.Itoa(a.AID)
+		manifest = append(manifest, manifestItem{id: id, href: ArticleFileName(a.AID), mediaType: "application/xhtml+xml"})
+		spine = append(spine, id)
+
+		entry := &navEntry{title: a.Title, href: ArticleFileName(a.AID)}
+		if a.SectionTitle == "" {
+			toc = append(toc, entry)
+			continue
+		}
+		if len(toc) == 0 || toc[len(toc)-1].title != a.SectionTitle || toc[len(toc)-1].href != "" {
+			toc = append(toc, &navEntry{title: a.SectionTitle})
+		}
+		section := toc[len(toc)-1]
+		section.children = append(section.children, entry)
+	}
+
+	// comments appendix
+	appendix := &navEntry{title: "附录: 精选留言"}
+	for _, a := range articles {
+		if !files.CheckFileExists(filepath.Join(dir, CommentsFileName(a.AID))) {
+			continue
+		}
+		id := "comments-" + strconv.Itoa(a.AID)
+		manifest = append(manifest, manifestItem{id: id, href: CommentsFileName(a.AID), mediaType: "application/xhtml+xml"})
+		spine = append(spine, id)
+		appendix.children = append(appendix.children, &navEntry{title: a.Title, href: CommentsFileName(a.AID)})
+	}
+	if len(appendix.children) > 0 {
+		toc = append(toc, appendix)
+	}
+
+	images, err := imageItems(dir)
+	if err != nil {
+		return "", err
+	}
+	manifest = append(manifest, images...)
+
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	generated[navName] = navXHTML(course.Title, toc)
+	generated[ncxName] = ncxXML(course, toc)
+	generated[styleName] = styleCSS
+	generated[opfName] = opfXML(course, manifest, spine, coverImage != "")
+	if coverImage != "" {
+		generated[coverName] = coverXHTML(course.Title, coverImage)
+	}
+
+	out := filepath.Join(filepath.Dir(dir), filenamify.Filenamify(course.Title)+EPUBExtension)
+	if err := writeContainer(out, dir, manifest, generated); err != nil {
+		_ = os.Remove(out)
+		return "", err
+	}
+	if err := Validate(out); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+// unlinkMissingChapters point links to chapters not packaged back to geektime, modified
+// chapters are put into generated
+func unlinkMissingChapters(dir string, articles []geektime.Article, generated map[string]string) error {
+	included := make(map[int]bool, len(articles))
+	for _, a := range articles {
+		included[a.AID] = true
+	}
+	for _, a := range articles {
+		name := ArticleFileName(a.AID)
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		changed := false
+		content := chapterLinkRegexp.ReplaceAllStringFunc(string(data), func(s string) string {
+			aid, _ := strconv.Atoi(chapterLinkRegexp.FindStringSubmatch(s)[1])
+			if included[aid] {
+				return s
+			}
+			changed = true
+			return `href="` + geektime.DefaultBaseURL + "/column/article/" + strconv.Itoa(aid) + `"`
+		})
+		if changed {
+			generated[name] = content
+		}
+	}
+	return nil
+}
+
+func writeContainer(out, dir string, manifest []manifestItem, generated map[string]string) error {
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+
+	// mimetype must be the first entry, stored without compression, extra field and data descriptor
+	w, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               "mimetype",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE([]byte(mimeType)),
+		CompressedSize64:   uint64(len(mimeType)),
+		UncompressedSize64: uint64(len(mimeType)),
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, mimeType); err != nil {
+		return err
+	}
+
+	if w, err = zw.Create("META-INF/container.xml"); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, containerXML); err != nil {
+		return err
+	}
+
+	names := []string{opfName}
+	for _, item := range manifest {
+		names = append(names, item.href)
+	}
+	for _, name := range names {
+		if w, err = zw.Create(contentDir + "/" + name); err != nil {
+			return err
+		}
+		if content, ok := generated[name]; ok {
+			_, err = io.WriteString(w, content)
+		} else {
+			err = copyFile(w, filepath.Join(dir, filepath.FromSlash(name)))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func copyFile(w io.Writer, fileName string) error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// imageItems list all images downloaded in images folder
+func imageItems(dir string) ([]manifestItem, error) {
+	var items []manifestItem
+	root := filepath.Join(dir, "images")
+	if !files.CheckFileExists(root) {
+		return nil, nil
+	}
+	err := filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			// image manifest and temp files
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		mediaType := mediaTypeOfFile(p)
+		if mediaType == "" {
+			logger.Warnf("Build epub skip unsupported image %s", p)
+			return nil
+		}
+		items = append(items, manifestItem{
+			id:        "img-" + strconv.Itoa(len(items)+1),
+			href:      filepath.ToSlash(rel),
+			mediaType: mediaType,
+		})
+		return nil
+	})
+	sort.SliceStable(items, func(i, j int) bool { return items[i].href < items[j].href })
+	return items, err
+}
+
+func downloadCover(ctx context.Context, coverURL, dir string) string {
+	if coverURL == "" {
+		return ""
+	}
+	ext := path.Ext(strings.Split(coverURL, "?")[0])
+	if mediaTypeByExt(ext) == "" {
+		ext = ".jpg"
+	}
+	name := "cover" + ext
+	dst := filepath.Join(dir, name)
+	if files.CheckFileExists(dst) {
+		return name
+	}
+	headers := map[string]string{
+		geektime.Origin:    geektime.DefaultBaseURL,
+		geektime.UserAgent: geektime.DefaultUserAgent,
+	}
+	if _, err := downloader.DownloadFileConcurrently(ctx, dst, coverURL, headers, 1); err != nil {
+		logger.Warnf("Download epub cover %s failed: %v", coverURL, err)
+		_ = os.Remove(dst)
+		return ""
+	}
+	return name
+}
+
+func mediaTypeByExt(name string) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".png":
+		return "image/png"
+	case ".gif":
+		return "image/gif"
+	case ".webp":
+		return "image/webp"
+	case ".svg":
+		return "image/svg+xml"
+	}
+	return ""
+}
+
+// mediaTypeOfFile sniff image content, file extension from url may be wrong
+func mediaTypeOfFile(fileName string) string {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	switch t := http.DetectContentType(head[:n]); t {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		return t
+	}
+	if mediaTypeByExt(fileName) == "image/svg+xml" {
+		return "image/svg+xml"
+	}
+	return ""
+}
+
+func opfXML(course geektime.Course, manifest []manifestItem, spine []string, hasCover bool) string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" xml:lang="zh-CN">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+`)
+	sb.WriteString(`    <dc:identifier id="book-id">` + bookID(course) + "</dc:identifier>\n")
+	sb.WriteString("    <dc:title>" + escapeXML(course.Title) + "</dc:title>\n")
+	sb.WriteString("    <dc:language>zh-CN</dc:language>\n")
+	if course.Author != "" {
+		sb.WriteString("    <dc:creator>" + escapeXML(course.Author) + "</dc:creator>\n")
+	}
+	sb.WriteString("    <dc:publisher>极客时间</dc:publisher>\n")
+	sb.WriteString("    <dc:source>" + geektime.DefaultBaseURL + "/column/intro/" + strconv.Itoa(course.ID) + "</dc:source>\n")
+	sb.WriteString(`    <meta property="dcterms:modified">` + time.Now().UTC().Format("2006-01-02T15:04:05Z") + "</meta>\n")
+	if hasCover {
+		// cover for epub2 readers
+		sb.WriteString(`    <meta name="cover" content="cover-image"/>` + "\n")
+	}
+	sb.WriteString("  </metadata>\n  <manifest>\n")
+	for _, item := range manifest {
+		sb.WriteString(`    <item id="` + item.id + `" href="` + escapeXML(item.href) + `" media-type="` + item.mediaType + `"`)
+		if item.properties != "" {
+			sb.WriteString(` properties="` + item.properties + `"`)
+		}
+		sb.WriteString("/>\n")
+	}
+	sb.WriteString("  </manifest>\n" + `  <spine toc="ncx">` + "\n")
+	for _, id := range spine {
+		sb.WriteString(`    <itemref idref="` + id + `"/>` + "\n")
+	}
+	sb.WriteString("  </spine>\n</package>\n")
+	return sb.String()
+}
+
+func bookID(course geektime.Course) string {
+	return "urn:geektime:course:" + strconv.Itoa(course.ID)
+}
+
+func navXHTML(title string, toc []*navEntry) string {
+	var sb strings.Builder
+	sb.WriteString(xhtmlHeader + escapeXML(title) + xhtmlHeaderEnd)
+	sb.WriteString(`<nav epub:type="toc" id="toc">` + "\n<h1>目录</h1>\n")
+	writeNavList(&sb, toc)
+	sb.WriteString("</nav>" + xhtmlFooter)
+	return sb.String()
+}
+
+func writeNavList(sb *strings.Builder, entries []*navEntry) {
+	sb.WriteString("<ol>\n")
+	for _, e := range entries {
+		sb.WriteString("<li>")
+		if e.href != "" {
+			sb.WriteString(`<a href="` + escapeXML(e.href) + `">` + escapeXML(e.title) + "</a>")
+		} else {
+			sb.WriteString("<span>" + escapeXML(e.title) + "</span>")
+		}
+		if len(e.children) > 0 {
+			sb.WriteString("\n")
+			writeNavList(sb, e.children)
+		}
+		sb.WriteString("</li>\n")
+	}
+	sb.WriteString("</ol>\n")
+}
+
+func ncxXML(course geektime.Course, toc []*navEntry) string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">
+  <head>
+`)
+	sb.WriteString(`    <meta name="dtb:uid" content="` + bookID(course) + `"/>` + "\n")
+	sb.WriteString("  </head>\n  <docTitle><text>" + escapeXML(course.Title) + "</text></docTitle>\n  <navMap>\n")
+	order, id := 0, 0
+	var write func(entries []*navEntry, depth int, sameAsParent bool)
+	write = func(entries []*navEntry, depth int, sameAsParent bool) {
+		indent := strings.Repeat("  ", depth+2)
+		for i, e := range entries {
+			// nav points refer to the same content share the same play order
+			if i > 0 || !sameAsParent {
+				order++
+			}
+			href := e.href
+			if href == "" && len(e.children) > 0 {
+				// ncx requires content src, point section to its first article
+				href = e.children[0].href
+			}
+			id++
+			sb.WriteString(fmt.Sprintf("%s<navPoint id=\"nav-%d\" playOrder=\"%d\">\n", indent, id, order))
+			sb.WriteString(indent + "  <navLabel><text>" + escapeXML(e.title) + "</text></navLabel>\n")
+			sb.WriteString(indent + `  <content src="` + escapeXML(href) + `"/>` + "\n")
+			write(e.children, depth+1, e.href == "")
+			sb.WriteString(indent + "</navPoint>\n")
+		}
+	}
+	write(toc, 0, false)
+	sb.WriteString("  </navMap>\n</ncx>\n")
+	return sb.String()
+}
+
+func coverXHTML(title, image string) string {
+	return xhtmlHeader + escapeXML(title) + xhtmlHeaderEnd +
+		`<section epub:type="cover" class="cover"><img src="` + escapeXML(image) + `" alt="` + escapeXML(title) + `"/></section>` +
+		xhtmlFooter
+}
@@ -0,0 +1,226 @@
+package epub
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// ErrInvalidEPUB ...
+var ErrInvalidEPUB = errors.New("EPUB 文件校验失败")
+
+type opfPackage struct {
+	UniqueIdentifier string `xml:"unique-identifier,attr"`
+	Metadata         struct {
+		Identifiers []struct {
+			ID    string `xml:"id,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"identifier"`
+		Titles    []string `xml:"title"`
+		Languages []string `xml:"language"`
+		Metas     []struct {
+			Property string `xml:"property,attr"`
+			Value    string `xml:",chardata"`
+		} `xml:"meta"`
+	} `xml:"metadata"`
+	Manifest struct {
+		Items []struct {
+			ID         string `xml:"id,attr"`
+			Href       string `xml:"href,attr"`
+			MediaType  string `xml:"media-type,attr"`
+			Properties string `xml:"properties,attr"`
+		} `xml:"item"`
+	} `xml:"manifest"`
+	Spine struct {
+		Toc      string `xml:"toc,attr"`
+		ItemRefs []struct {
+			IDRef string `xml:"idref,attr"`
+		} `xml:"itemref"`
+	} `xml:"spine"`
+}
+
+type container struct {
+	RootFiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+// Validate check epub against the main OCF and package document rules of EPUB 3 spec:
+// mimetype entry, container, required metadata, manifest and spine references,
+// well-formed content documents and local resources declared in manifest.
+func Validate(fileName string) error {
+	zr, err := zip.OpenReader(fileName)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	var errs []error
+	report := func(format string, a ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, a...))
+	}
+
+	entries := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		entries[f.Name] = f
+	}
+
+	if len(zr.File) == 0 || zr.File[0].Name != "mimetype" {
+		report("mimetype 必须是第一个文件")
+	} else {
+		f := zr.File[0]
+		content, _ := readZipFile(f)
+		if f.Method != zip.Store || len(f.Extra) > 0 || string(content) != mimeType {
+			report("mimetype 文件必须不压缩且内容为 %s", mimeType)
+		}
+	}
+
+	var c container
+	if err := unmarshalZipFile(entries["META-INF/container.xml"], &c); err != nil || len(c.RootFiles) == 0 {
+		report("META-INF/container.xml 无效: %v", err)
+		return errors.Join(append([]error{ErrInvalidEPUB}, errs...)...)
+	}
+	opfPath := c.RootFiles[0].FullPath
+	var pkg opfPackage
+	if err := unmarshalZipFile(entries[opfPath], &pkg); err != nil {
+		report("%s 无效: %v", opfPath, err)
+		return errors.Join(append([]error{ErrInvalidEPUB}, errs...)...)
+	}
+
+	hasIdentifier := false
+	for _, id := range pkg.Metadata.Identifiers {
+		if id.ID == pkg.UniqueIdentifier && strings.TrimSpace(id.Value) != "" {
+			hasIdentifier = true
+		}
+	}
+	if !hasIdentifier {
+		report("缺少 unique-identifier 对应的 dc:identifier")
+	}
+	if len(pkg.Metadata.Titles) == 0 {
+		report("缺少 dc:title")
+	}
+	if len(pkg.Metadata.Languages) == 0 {
+		report("缺少 dc:language")
+	}
+	hasModified := false
+	for _, m := range pkg.Metadata.Metas {
+		if m.Property == "dcterms:modified" {
+			hasModified = true
+		}
+	}
+	if !hasModified {
+		report("缺少 dcterms:modified")
+	}
+
+	baseDir := path.Dir(opfPath)
+	ids := make(map[string]bool)
+	hrefs := make(map[string]bool)
+	navCount := 0
+	for _, item := range pkg.Manifest.Items {
+		if ids[item.ID] {
+			report("manifest id 重复: %s", item.ID)
+		}
+		ids[item.ID] = true
+		full := path.Join(baseDir, item.Href)
+		hrefs[full] = true
+		if entries[full] == nil {
+			report("manifest 中的文件不存在: %s", item.Href)
+		}
+		if strings.Contains(item.Properties, "nav") {
+			navCount++
+		}
+	}
+	if navCount != 1 {
+		report("必须有且只有一个 nav 文档")
+	}
+	if len(pkg.Spine.ItemRefs) == 0 {
+		report("spine 为空")
+	}
+	for _, ref := range pkg.Spine.ItemRefs {
+		if !ids[ref.IDRef] {
+			report("spine 引用了不存在的 manifest id: %s", ref.IDRef)
+		}
+	}
+	if pkg.Spine.Toc != "" && !ids[pkg.Spine.Toc] {
+		report("spine toc 引用了不存在的 manifest id: %s", pkg.Spine.Toc)
+	}
+
+	for _, item := range pkg.Manifest.Items {
+		f := entries[path.Join(baseDir, item.Href)]
+		if f == nil || !strings.HasSuffix(item.MediaType, "xml") {
+			continue
+		}
+		for _, err := range checkXMLDocument(f, hrefs) {
+			report("%s: %v", item.Href, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(append([]error{ErrInvalidEPUB}, errs...)...)
+	}
+	return nil
+}
+
+// checkXMLDocument check document is well-formed and local resources it refers to are in manifest
+func checkXMLDocument(f *zip.File, manifest map[string]bool) []error {
+	rc, err := f.Open()
+	if err != nil {
+		return []error{err}
+	}
+	defer rc.Close()
+
+	var errs []error
+	dir := path.Dir(f.Name)
+	d := xml.NewDecoder(rc)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return append(errs, err)
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		for _, a := range se.Attr {
+			if a.Name.Local != "src" && !(a.Name.Local == "href" && se.Name.Local == "a") {
+				continue
+			}
+			u, err := url.Parse(a.Value)
+			if err != nil || u.Scheme != "" || u.Path == "" {
+				continue
+			}
+			if !manifest[path.Join(dir, u.Path)] {
+				errs = append(errs, fmt.Errorf("引用的文件未在 manifest 中声明: %s", a.Value))
+			}
+		}
+	}
+	return errs
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+func unmarshalZipFile(f *zip.File, v interface{}) error {
+	if f == nil {
+		return errors.New("文件不存在")
+	}
+	data, err := readZipFile(f)
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(data, v)
+}
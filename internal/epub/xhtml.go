@@ -0,0 +1,103 @@
+package epub
+
+import (
+	"io"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	xmlNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_.-]*$`)
+	// characters not allowed in XML 1.0
+	invalidXMLCharRegexp = regexp.MustCompile(`[\x00-\x08\x0B\x0C\x0E-\x1F\x{FFFE}\x{FFFF}]`)
+	xmlEscaper           = strings.NewReplacer(`&`, "&amp;", `<`, "&lt;", `>`, "&gt;", `"`, "&quot;", `'`, "&#39;")
+
+	voidElements = map[string]bool{
+		"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+		"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+	}
+	// elements removed with their children, remote media and scripts are not allowed in epub
+	droppedElements = map[string]bool{
+		"script": true, "style": true, "iframe": true, "video": true, "audio": true, "object": true,
+		"embed": true, "source": true, "track": true, "noscript": true, "form": true, "input": true,
+		"button": true, "select": true, "textarea": true, "link": true, "meta": true, "base": true,
+	}
+	// obsolete presentational elements, children are kept
+	unwrappedElements = map[string]bool{
+		"font": true, "center": true, "big": true, "strike": true, "tt": true,
+	}
+	// obsolete presentational attributes rejected by epubcheck
+	droppedAttributes = map[string]bool{
+		"align": true, "valign": true, "bgcolor": true, "border": true, "cellpadding": true,
+		"cellspacing": true, "frame": true, "rules": true, "face": true, "color": true, "size": true,
+		"nowrap": true, "char": true, "charoff": true, "hspace": true, "vspace": true, "srcset": true,
+		"contenteditable": true, "spellcheck": true, "draggable": true,
+	}
+)
+
+// writeXHTML serialize html node and its children as well-formed xhtml
+func writeXHTML(w io.StringWriter, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		_, _ = w.WriteString(escapeXML(n.Data))
+		return
+	case html.DocumentNode:
+		writeChildrenXHTML(w, n)
+		return
+	case html.ElementNode:
+	default:
+		// comments and doctype
+		return
+	}
+
+	name := strings.ToLower(n.Data)
+	if droppedElements[name] || !xmlNameRegexp.MatchString(name) {
+		return
+	}
+	if unwrappedElements[name] {
+		writeChildrenXHTML(w, n)
+		return
+	}
+
+	_, _ = w.WriteString("<" + name)
+	hasAlt := false
+	seen := make(map[string]bool)
+	for _, a := range n.Attr {
+		key := strings.ToLower(a.Key)
+		if a.Namespace != "" || seen[key] || droppedAttributes[key] || strings.HasPrefix(key, "on") ||
+			strings.HasPrefix(key, "xmlns") || !xmlNameRegexp.MatchString(key) {
+			continue
+		}
+		if (key == "width" || key == "height") && name != "img" {
+			continue
+		}
+		seen[key] = true
+		if key == "alt" {
+			hasAlt = true
+		}
+		_, _ = w.WriteString(" " + key + `="` + escapeXML(a.Val) + `"`)
+	}
+	if name == "img" && !hasAlt {
+		_, _ = w.WriteString(` alt=""`)
+	}
+
+	if voidElements[name] {
+		_, _ = w.WriteString("/>")
+		return
+	}
+	_, _ = w.WriteString(">")
+	writeChildrenXHTML(w, n)
+	_, _ = w.WriteString("</" + name + ">")
+}
+
+func writeChildrenXHTML(w io.StringWriter, n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		writeXHTML(w, c)
+	}
+}
+
+func escapeXML(s string) string {
+	return xmlEscaper.Replace(invalidXMLCharRegexp.ReplaceAllString(s, ""))
+}
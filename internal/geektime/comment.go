@@ -0,0 +1,153 @@
+package geektime
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/nicoxiang/geektime-downloader/internal/geektime/response"
+)
+
+const (
+	// V1CommentsPath get article comments, paged by score of last comment
+	V1CommentsPath = "/serv/v1/comments"
+	// V1DiscussionChildListPath get replies of one comment
+	V1DiscussionChildListPath = "/serv/discussion/v1/child_list"
+
+	discussionPageSize = 20
+)
+
+// Comment ...
+type Comment struct {
+	ID        int       `json:"id"`
+	UserName  string    `json:"user_name"`
+	Content   string    `json:"content"`
+	CreatedAt int64     `json:"created_at"`
+	LikeCount int       `json:"like_count"`
+	IsTop     bool      `json:"is_top,omitempty"`
+	Replies   []Comment `json:"replies,omitempty"`
+	// IsAuthor means reply from course author or geektime editor
+	IsAuthor bool `json:"is_author,omitempty"`
+	// ReplyTo user name replied to, only for replies
+	ReplyTo string `json:"reply_to,omitempty"`
+}
+
+// DisplayName return name shown in comment header, replies from author are marked and name of user
+// replied to is appended
+func (c Comment) DisplayName() string {
+	name := c.UserName
+	if c.IsAuthor {
+		name = "作者回复"
+		if c.UserName != "" {
+			name += " " + c.UserName
+		}
+	}
+	if c.ReplyTo != "" {
+		name += " 回复 " + c.ReplyTo
+	}
+	return name
+}
+
+// Date return local date comment was created at, e.g. 2006-01-02
+func (c Comment) Date() string {
+	return time.Unix(c.CreatedAt, 0).Format("2006-01-02")
+}
+
+// ArticleComments get all comments of article, including replies and author responses
+func (c *Client) ArticleComments(articleID int) ([]Comment, error) {
+	var comments []Comment
+	var prev int64
+	for {
+		var res response.V1CommentsResponse
+		r := c.newRequest(
+			resty.MethodPost,
+			DefaultBaseURL,
+			V1CommentsPath,
+			nil,
+			map[string]interface{}{
+				"aid":  strconv.Itoa(articleID),
+				"prev": prev,
+			},
+			&res,
+		)
+		if _, err := do(r); err != nil {
+			return nil, err
+		}
+
+		for _, v := range res.Data.List {
+			comment := Comment{
+				ID:        v.ID,
+				UserName:  v.UserName,
+				Content:   v.CommentContent,
+				CreatedAt: v.CommentCtime,
+				LikeCount: v.LikeCount,
+				IsTop:     v.CommentIsTop,
+			}
+			for _, reply := range v.Replies {
+				comment.Replies = append(comment.Replies, Comment{
+					ID:        reply.ID,
+					UserName:  reply.UserName,
+					Content:   reply.Content,
+					CreatedAt: reply.Ctime,
+					IsAuthor:  true,
+				})
+			}
+			if v.DiscussionCount > 0 {
+				discussions, err := c.commentDiscussions(v.ID)
+				if err != nil {
+					return nil, err
+				}
+				comment.Replies = append(comment.Replies, discussions...)
+			}
+			comments = append(comments, comment)
+			prev = v.Score
+		}
+
+		if !res.Data.Page.More || len(res.Data.List) == 0 {
+			break
+		}
+	}
+	return comments, nil
+}
+
+// commentDiscussions get all replies of one comment
+func (c *Client) commentDiscussions(commentID int) ([]Comment, error) {
+	var replies []Comment
+	var prev int64
+	for {
+		var res response.V1DiscussionChildListResponse
+		r := c.newRequest(
+			resty.MethodPost,
+			DefaultBaseURL,
+			V1DiscussionChildListPath,
+			nil,
+			map[string]interface{}{
+				"discussion_id": commentID,
+				"prev":          prev,
+				"size":          discussionPageSize,
+			},
+			&res,
+		)
+		if _, err := do(r); err != nil {
+			return nil, err
+		}
+
+		for _, v := range res.Data.List {
+			replies = append(replies, Comment{
+				ID:        v.Discussion.ID,
+				UserName:  v.Author.Nickname,
+				Content:   v.Discussion.DiscussionContent,
+				CreatedAt: v.Discussion.Ctime,
+				LikeCount: v.Discussion.LikesNumber,
+				IsAuthor:  v.IsAuthor,
+				ReplyTo:   v.ReplyAuthor.Nickname,
+			})
+			prev = v.Score
+		}
+
+		if !res.Data.Page.More || len(res.Data.List) == 0 {
+			break
+		}
+	}
+	return replies, nil
+}
@@ -1,6 +1,8 @@
 package geektime
 
 import (
+	"fmt"
+	"html"
 	"strconv"
 
 	"github.com/go-resty/resty/v2"
@@ -20,23 +22,69 @@ const (
 	V1EnterpriseVideoPlayAuthPath = "/app/v1/source_auth/video_play_auth"
 )
 
-// EnterpriseCourseInfo get enterprise course info
+const (
+	// EnterpriseProductTypeDailyLesson is product type of enterprise daily lesson
+	EnterpriseProductTypeDailyLesson = "d"
+	// EnterpriseProductTypeCase is product type of enterprise case study
+	EnterpriseProductTypeCase = "q"
+)
+
+// EnterpriseCourseInfo get enterprise course info. Daily lesson is a single video whose article
+// is in product info, articles of other products including case study are listed by course
+// articles endpoint.
 func (c *Client) EnterpriseCourseInfo(id int) (Course, error) {
-	var p Course
-	var err error
-	p, err = c.enterpriseCourseInfo(id)
+	p, res, err := c.enterpriseCourseInfo(id)
 	if err != nil {
 		return p, err
 	}
 
-	var articles []Article
-	articles, err = c.enterpriseCourseArticles(id)
-	if err != nil {
-		return p, err
+	if p.Type == EnterpriseProductTypeDailyLesson {
+		aid, err := strconv.Atoi(res.Data.DL.Article.ArticleID)
+		if err != nil || aid <= 0 {
+			return p, fmt.Errorf("每日一课 %d 缺少视频文章", id)
+		}
+		p.Articles = []Article{{AID: aid, Title: res.Data.Title}}
+		p.IsVideo = true
+		return p, nil
 	}
-	p.Articles = articles
 
-	return p, nil
+	p.Articles, p.IsVideo, err = c.enterpriseCourseArticles(id)
+	return p, err
+}
+
+// EnterpriseArticle is enterprise text article detail in the same shape as V1ArticleInfo, so
+// that it can be exported like articles of normal columns
+type EnterpriseArticle struct {
+	response.V1ArticleResponse
+	// ContentMD is native markdown of article, empty if only html is returned
+	ContentMD string
+}
+
+// EnterpriseArticleInfo get enterprise text article detail
+func (c *Client) EnterpriseArticleInfo(articleID int) (EnterpriseArticle, error) {
+	var article EnterpriseArticle
+	detail, err := c.V1EnterpriseArticleDetail(strconv.Itoa(articleID))
+	if err != nil {
+		return article, err
+	}
+	d := detail.Data
+	article.ContentMD = d.Article.ContentMD
+	res := &article.V1ArticleResponse
+	res.Code = detail.Code
+	res.Data.ArticleTitle = d.Article.Title
+	res.Data.ArticleContent = d.Article.Content
+	if res.Data.ArticleContent == "" && d.Article.Summary != "" {
+		// video of daily lesson and case study only has summary
+		res.Data.ArticleContent = "<p>" + html.EscapeString(d.Article.Summary) + "</p>"
+	}
+	res.Data.ArticleSummary = d.Article.Summary
+	res.Data.ArticleCtime = d.Article.CTime
+	res.Data.AuthorName = d.Author.Name
+	res.Data.AudioDubber = d.Audio.Dubber
+	res.Data.AudioDownloadURL = d.Audio.DownloadURL
+	res.Data.ColumnCover = d.Article.Cover.ColumnCover
+	res.Data.VideoID = d.Video.ID
+	return article, nil
 }
 
 // V1EnterpriseArticleDetail get enterprise article detail
@@ -78,7 +126,7 @@ func (c *Client) EnterpriseVideoPlayAuth(articleID, videoID string) (string, err
 	return res.Data.PlayAuth, nil
 }
 
-func (c *Client) enterpriseCourseInfo(productID int) (Course, error) {
+func (c *Client) enterpriseCourseInfo(productID int) (Course, response.V1EnterpriseProductInfoResponse, error) {
 	var res response.V1EnterpriseProductInfoResponse
 
 	r := c.newRequest(
@@ -93,19 +141,26 @@ func (c *Client) enterpriseCourseInfo(productID int) (Course, error) {
 	)
 
 	if _, err := do(r); err != nil {
-		return Course{}, err
+		return Course{}, res, err
 	}
 
-	return Course{
-		Access:  res.Data.Extra.IsMyCourse,
-		ID:      productID,
-		Title:   res.Data.Title,
-		Type:    "",
-		IsVideo: true,
-	}, nil
+	p := Course{
+		Access:      res.Data.Extra.IsMyCourse,
+		ID:          productID,
+		Title:       res.Data.Title,
+		Author:      res.Data.Author.Name,
+		Cover:       res.Data.Cover.Square,
+		Subtitle:    res.Data.SubTitle,
+		AuthorIntro: res.Data.Author.Intro,
+		AuthorBrief: res.Data.Author.Brief,
+		Type:        res.Data.ProductType,
+	}
+	return p, res, nil
 }
 
-func (c *Client) enterpriseCourseArticles(productID int) ([]Article, error) {
+// enterpriseCourseArticles return articles of course and whether it is a video course, 体系课 and
+// 生态课 may be text courses whose articles have no video
+func (c *Client) enterpriseCourseArticles(productID int) ([]Article, bool, error) {
 	var res response.V1EnterpriseArticlesResponse
 	r := c.newRequest(
 		resty.MethodPost,
@@ -119,20 +174,31 @@ func (c *Client) enterpriseCourseArticles(productID int) ([]Article, error) {
 	)
 
 	if _, err := do(r); err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	var articles []Article
+	articles, isVideo := enterpriseArticles(res)
+	return articles, isVideo, nil
+}
 
+// enterpriseArticles convert article list of enterprise course, articles without video are text
+// articles. Course is a video course only if all its articles are videos, video articles of text
+// courses are downloaded along with the text.
+func enterpriseArticles(res response.V1EnterpriseArticlesResponse) ([]Article, bool) {
+	var articles []Article
+	isVideo := true
 	for _, sections := range res.Data.List {
 		for _, a := range sections.ArticleList {
 			articleID, _ := strconv.Atoi(a.Article.ID)
+			isText := a.Video.ID == ""
+			isVideo = isVideo && !isText
 			articles = append(articles, Article{
 				AID:          articleID,
 				SectionTitle: sections.Title,
 				Title:        a.Article.Title,
+				IsText:       isText,
 			})
 		}
 	}
-	return articles, nil
+	return articles, len(articles) > 0 && isVideo
 }
@@ -5,6 +5,8 @@ import (
 
 	"github.com/go-resty/resty/v2"
 	"github.com/nicoxiang/geektime-downloader/internal/geektime/response"
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/filenamify"
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/logger"
 )
 
 const (
@@ -13,6 +15,8 @@ const (
 
 	// V1ColumnArticlesPath get all articles summary info in one column
 	V1ColumnArticlesPath = "/serv/v1/column/articles"
+	// V1ChaptersPath get chapters of normal column
+	V1ChaptersPath = "/serv/v1/chapters"
 	// V1ArticlePath used in normal column
 	V1ArticlePath = "/serv/v1/article"
 	// V3ColumnInfoPath used in get normal column/video info
@@ -35,12 +39,17 @@ const (
 
 // Course ...
 type Course struct {
-	Access   bool
-	ID       int
-	Title    string
-	Type     string
-	IsVideo  bool
-	Articles []Article
+	Access      bool
+	ID          int
+	Title       string
+	Author      string
+	Cover       string
+	Subtitle    string
+	AuthorIntro string
+	AuthorBrief string
+	Type        string
+	IsVideo     bool
+	Articles    []Article
 }
 
 // Article ...
@@ -48,6 +57,23 @@ type Article struct {
 	AID          int
 	SectionTitle string
 	Title        string
+	// IsText mark text article of course mixing text and video, e.g. reading material of university
+	// course or article without video of enterprise course
+	IsText bool
+	// Name is path of article files relative to course dir without extension, set by path
+	// template. Use FileName to get it
+	Name string
+
+	chapterID string
+}
+
+// FileName return path of article files relative to course dir without extension, file name of
+// title is used if article is not named by path template
+func (a Article) FileName() string {
+	if a.Name != "" {
+		return a.Name
+	}
+	return filenamify.Filenamify(a.Title)
 }
 
 // CourseInfo get narmal geektime course info
@@ -64,7 +90,12 @@ func (c *Client) CourseInfo(productID int) (Course, error) {
 	if err != nil {
 		return p, err
 	}
-	p.Articles = articles
+	// chapters only group articles, course can still be downloaded without them
+	chapters, err := c.columnChapters(productID)
+	if err != nil {
+		logger.Warnf("Get chapters of course %d failed: %v", productID, err)
+	}
+	p.Articles = groupByChapter(articles, chapters)
 
 	return p, nil
 }
@@ -173,7 +204,13 @@ func (c *Client) columnInfo(productID int) (Course, error) {
 		ID:      res.Data.ID,
 		Type:    res.Data.Type,
 		Title:   res.Data.Title,
+		Author:  res.Data.Author.Name,
+		Cover:   res.Data.Cover.Square,
 		IsVideo: res.Data.IsVideo,
+
+		Subtitle:    res.Data.Subtitle,
+		AuthorIntro: res.Data.Author.Intro,
+		AuthorBrief: res.Data.Author.Brief,
 	}, nil
 }
 
@@ -201,9 +238,68 @@ func (c *Client) columnArticles(cid int) ([]Article, error) {
 	var articles []Article
 	for _, v := range res.Data.List {
 		articles = append(articles, Article{
-			AID:   v.ID,
-			Title: v.ArticleTitle,
+			AID:       v.ID,
+			Title:     v.ArticleTitle,
+			chapterID: v.ChapterID,
 		})
 	}
 	return articles, nil
 }
+
+// chapter of normal column
+type chapter struct {
+	id    string
+	title string
+}
+
+// columnChapters call geektime api to get chapters in order, columns without chapters return none
+func (c *Client) columnChapters(cid int) ([]chapter, error) {
+	var res response.V1ChaptersResponse
+	r := c.newRequest(
+		resty.MethodPost,
+		DefaultBaseURL,
+		V1ChaptersPath,
+		nil,
+		map[string]interface{}{
+			"cid": strconv.Itoa(cid),
+		},
+		&res,
+	)
+	if _, err := do(r); err != nil {
+		return nil, err
+	}
+
+	var chapters []chapter
+	for _, v := range res.Data {
+		chapters = append(chapters, chapter{id: v.ID, title: v.Title})
+	}
+	return chapters, nil
+}
+
+// groupByChapter set section title of articles and move them under their chapter in chapter
+// order, so that articles of a chapter are adjacent. Articles not in any chapter are kept at the
+// end in original order.
+func groupByChapter(articles []Article, chapters []chapter) []Article {
+	if len(chapters) == 0 {
+		return articles
+	}
+	position := make(map[string]int, len(chapters))
+	for i, ch := range chapters {
+		position[ch.id] = i
+	}
+	grouped := make([][]Article, len(chapters)+1)
+	for _, a := range articles {
+		i, ok := position[a.chapterID]
+		if !ok {
+			i = len(chapters)
+		} else {
+			a.SectionTitle = chapters[i].title
+		}
+		grouped[i] = append(grouped[i], a)
+	}
+	result := make([]Article, 0, len(articles))
+	for _, g := range grouped {
+		result = append(result, g...)
+	}
+	return result
+}
@@ -0,0 +1,95 @@
+package geektime
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/nicoxiang/geektime-downloader/internal/geektime/response"
+)
+
+func TestGroupByChapter(t *testing.T) {
+	articles := []Article{
+		{AID: 1, Title: "开篇词", chapterID: "10"},
+		{AID: 2, Title: "基础 1", chapterID: "20"},
+		{AID: 3, Title: "加餐", chapterID: "30"},
+		{AID: 4, Title: "番外", chapterID: "99"},
+		{AID: 5, Title: "基础 2", chapterID: "20"},
+		{AID: 6, Title: "结束语", chapterID: "40"},
+	}
+	chapters := []chapter{
+		{id: "10", title: "开篇词"},
+		{id: "20", title: "基础篇"},
+		{id: "30", title: "加餐"},
+		{id: "40", title: "结束语"},
+	}
+
+	var got []int
+	var sections []string
+	for _, a := range groupByChapter(articles, chapters) {
+		got = append(got, a.AID)
+		sections = append(sections, a.SectionTitle)
+	}
+	if want := []int{1, 2, 5, 3, 6, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+	if want := []string{"开篇词", "基础篇", "基础篇", "加餐", "结束语", ""}; !reflect.DeepEqual(sections, want) {
+		t.Errorf("sections = %q, want %q", sections, want)
+	}
+
+	if got := groupByChapter(articles, nil); !reflect.DeepEqual(got, articles) {
+		t.Errorf("articles without chapters changed: %v", got)
+	}
+}
+
+func TestEnterpriseArticles(t *testing.T) {
+	parse := func(s string) response.V1EnterpriseArticlesResponse {
+		var res response.V1EnterpriseArticlesResponse
+		if err := json.Unmarshal([]byte(s), &res); err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	// half of the articles are videos
+	articles, isVideo := enterpriseArticles(parse(`{"data": {"list": [
+		{"title": "第一章", "article_list": [
+			{"article": {"id": "1", "title": "视频"}, "video": {"id": "v1"}},
+			{"article": {"id": "2", "title": "文字"}}
+		]}
+	]}}`))
+	if isVideo {
+		t.Error("course with text articles should not be a video course")
+	}
+	if len(articles) != 2 || articles[0].IsText || !articles[1].IsText || articles[1].SectionTitle != "第一章" {
+		t.Errorf("articles = %+v", articles)
+	}
+
+	_, isVideo = enterpriseArticles(parse(`{"data": {"list": [{"article_list": [
+		{"article": {"id": "1"}, "video": {"id": "v1"}},
+		{"article": {"id": "2"}, "video": {"id": "v2"}}
+	]}]}}`))
+	if !isVideo {
+		t.Error("course with only video articles should be a video course")
+	}
+
+	if _, isVideo = enterpriseArticles(parse(`{"data": {"list": []}}`)); isVideo {
+		t.Error("empty course should not be a video course")
+	}
+}
+
+func TestCommentDisplayName(t *testing.T) {
+	for _, c := range []struct {
+		comment Comment
+		want    string
+	}{
+		{Comment{UserName: "张三"}, "张三"},
+		{Comment{IsAuthor: true}, "作者回复"},
+		{Comment{UserName: "编辑", IsAuthor: true, ReplyTo: "张三"}, "作者回复 编辑 回复 张三"},
+		{Comment{UserName: "李四", ReplyTo: "张三"}, "李四 回复 张三"},
+	} {
+		if got := c.comment.DisplayName(); got != c.want {
+			t.Errorf("DisplayName() of %+v = %q, want %q", c.comment, got, c.want)
+		}
+	}
+}
@@ -10,7 +10,7 @@ type V1ArticleResponse struct {
 		// ArticleCover    string        `json:"article_cover"`
 		// Subtitles       []interface{} `json:"subtitles"`
 		// ProductType     string        `json:"product_type"`
-		// AudioDubber     string        `json:"audio_dubber"`
+		AudioDubber string `json:"audio_dubber"`
 		// IsFinished      bool          `json:"is_finished"`
 		// Like            struct {
 		// 	HadDone bool `json:"had_done"`
@@ -46,10 +46,10 @@ type V1ArticleResponse struct {
 		// ArticleCshort        string        `json:"article_cshort"`
 		// VideoWidth           int           `json:"video_width"`
 		// ColumnCouldSub       bool          `json:"column_could_sub"`
-		// VideoID              string        `json:"video_id"`
+		VideoID string `json:"video_id"`
 		// Sku                  string        `json:"sku"`
 		// VideoCover           string        `json:"video_cover"`
-		// AuthorName           string        `json:"author_name"`
+		AuthorName string `json:"author_name"`
 		// ColumnIsOnboard      bool          `json:"column_is_onboard"`
 		InlineVideoSubtitles []struct {
 			VideoURL          string `json:"video_url"`
@@ -62,7 +62,7 @@ type V1ArticleResponse struct {
 		// AudioURL             string        `json:"audio_url"`
 		// ChapterID            string        `json:"chapter_id"`
 		// ColumnHadSub         bool          `json:"column_had_sub"`
-		// ColumnCover          string        `json:"column_cover"`
+		ColumnCover string `json:"column_cover"`
 		// Neighbors            struct {
 		// 	Left  []interface{} `json:"left"`
 		// 	Right struct {
@@ -109,7 +109,7 @@ type V1ArticleResponse struct {
 		// ID                  int    `json:"id"`
 		// FreeGet             bool   `json:"free_get"`
 		// IsVideoPreview      bool   `json:"is_video_preview"`
-		// ArticleSummary      string `json:"article_summary"`
+		ArticleSummary string `json:"article_summary"`
 		// ColumnSaleType      int    `json:"column_sale_type"`
 		// FloatQrcodeJump     string `json:"float_qrcode_jump"`
 		// ColumnID            int    `json:"column_id"`
@@ -130,7 +130,7 @@ type V1ArticleResponse struct {
 		// HlsVideos        []interface{} `json:"hls_videos"`
 		// InPvip           int           `json:"in_pvip"`
 		AudioDownloadURL string `json:"audio_download_url"`
-		// ArticleCtime     int           `json:"article_ctime"`
+		ArticleCtime     int    `json:"article_ctime"`
 		// VideoHeight      int           `json:"video_height"`
 	} `json:"data"`
 	Code int `json:"code"`
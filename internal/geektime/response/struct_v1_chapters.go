@@ -0,0 +1,11 @@
+package response
+
+// V1ChaptersResponse ...
+type V1ChaptersResponse struct {
+	Code int `json:"code"`
+	Data []struct {
+		ID           string `json:"id"`
+		Title        string `json:"title"`
+		ArticleCount int    `json:"article_count"`
+	} `json:"data"`
+}
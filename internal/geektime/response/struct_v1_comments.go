@@ -0,0 +1,65 @@
+package response
+
+// V1CommentsResponse ...
+type V1CommentsResponse struct {
+	Code int `json:"code"`
+	Data struct {
+		List []struct {
+			ID       int    `json:"id"`
+			UserName string `json:"user_name"`
+			// UserHeader     string `json:"user_header"`
+			CommentContent string `json:"comment_content"`
+			CommentCtime   int64  `json:"comment_ctime"`
+			CommentIsTop   bool   `json:"comment_is_top"`
+			LikeCount      int    `json:"like_count"`
+			// HadLiked       bool   `json:"had_liked"`
+			// IPAddress      string `json:"ip_address"`
+			Score           int64 `json:"score"`
+			DiscussionCount int   `json:"discussion_count"`
+			// replies from course author or geektime editor
+			Replies []struct {
+				ID       int    `json:"id"`
+				Content  string `json:"content"`
+				UserName string `json:"user_name"`
+				Ctime    int64  `json:"ctime"`
+				// UserNameReal string `json:"user_name_real"`
+				// Uid          int    `json:"uid"`
+				// Utype        int    `json:"utype"`
+			} `json:"replies"`
+		} `json:"list"`
+		Page struct {
+			Count int  `json:"count"`
+			More  bool `json:"more"`
+		} `json:"page"`
+	} `json:"data"`
+}
+
+// V1DiscussionChildListResponse ...
+type V1DiscussionChildListResponse struct {
+	Code int `json:"code"`
+	Data struct {
+		List []struct {
+			Author struct {
+				// ID       int    `json:"id"`
+				Nickname string `json:"nickname"`
+				// Avatar   string `json:"avatar"`
+			} `json:"author"`
+			Discussion struct {
+				ID                int    `json:"id"`
+				DiscussionContent string `json:"discussion_content"`
+				Ctime             int64  `json:"ctime"`
+				LikesNumber       int    `json:"likes_number"`
+				// IPAddress         string `json:"ip_address"`
+			} `json:"discussion"`
+			ReplyAuthor struct {
+				Nickname string `json:"nickname"`
+			} `json:"reply_author"`
+			// true when the reply comes from course author
+			IsAuthor bool  `json:"is_author"`
+			Score    int64 `json:"score"`
+		} `json:"list"`
+		Page struct {
+			More bool `json:"more"`
+		} `json:"page"`
+	} `json:"data"`
+}
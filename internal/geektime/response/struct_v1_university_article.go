@@ -0,0 +1,18 @@
+package response
+
+// V1UniversityArticleResponse ...
+type V1UniversityArticleResponse struct {
+	Code int `json:"code"`
+	Data struct {
+		ArticleID      int    `json:"article_id"`
+		ArticleTitle   string `json:"article_title"`
+		ArticleContent string `json:"article_content"`
+		ArticleCtime   int    `json:"article_ctime"`
+		AuthorName     string `json:"author_name"`
+		VideoTime      int    `json:"video_time"`
+	} `json:"data"`
+	Error struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	} `json:"error"`
+}
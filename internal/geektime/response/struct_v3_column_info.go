@@ -27,23 +27,23 @@ type V3ColumnInfoResponse struct {
 		// IsDailylesson    bool   `json:"is_dailylesson"`
 		// IsOpencourse     bool   `json:"is_opencourse"`
 		Title            string `json:"title"`
-		// Subtitle         string `json:"subtitle"`
+		Subtitle         string `json:"subtitle"`
 		// Ctime            int    `json:"ctime"`
 		// Unit             string `json:"unit"`
-		// Cover            struct {
-		// 	Square      string `json:"square"`
-		// 	Rectangle   string `json:"rectangle"`
-		// 	Horizontal  string `json:"horizontal"`
-		// 	Transparent string `json:"transparent"`
-		// 	Color       string `json:"color"`
-		// } `json:"cover"`
-		// Author struct {
-		// 	Name      string `json:"name"`
-		// 	Intro     string `json:"intro"`
-		// 	Avatar    string `json:"avatar"`
-		// 	BriefHTML string `json:"brief_html"`
-		// 	Brief     string `json:"brief"`
-		// } `json:"author"`
+		Cover struct {
+			Square string `json:"square"`
+			// Rectangle   string `json:"rectangle"`
+			// Horizontal  string `json:"horizontal"`
+			// Transparent string `json:"transparent"`
+			// Color       string `json:"color"`
+		} `json:"cover"`
+		Author struct {
+			Name string `json:"name"`
+			Intro     string `json:"intro"`
+			// Avatar    string `json:"avatar"`
+			// BriefHTML string `json:"brief_html"`
+			Brief     string `json:"brief"`
+		} `json:"author"`
 		// Price struct {
 		// 	Market       int `json:"market"`
 		// 	Sale         int `json:"sale"`
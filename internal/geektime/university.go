@@ -13,6 +13,8 @@ const (
 	UniversityV1VideoPlayAuthPath = "/serv/v1/video/play-auth"
 	// UniversityV1MyClassInfoPath get university class info and all articles info in it
 	UniversityV1MyClassInfoPath = "/serv/v1/myclass/info"
+	// UniversityV1ArticleInfoPath get university text lesson content
+	UniversityV1ArticleInfoPath = "/serv/v1/myclass/article"
 )
 
 // UniversityCourseInfo get university class info
@@ -54,13 +56,13 @@ func (c *Client) UniversityCourseInfo(classID int) (Course, error) {
 	var articles []Article
 	for _, lesson := range res.Data.Lessons {
 		for _, article := range lesson.Articles {
-			// ONLY download university video lessons
-			if article.VideoTime > 0 {
-				articles = append(articles, Article{
-					AID:   article.ArticleID,
-					Title: article.ArticleTitle,
-				})
-			}
+			// lessons without video are reading material, exercises and notes
+			articles = append(articles, Article{
+				AID:          article.ArticleID,
+				SectionTitle: lesson.ChapterName,
+				Title:        article.ArticleTitle,
+				IsText:       article.VideoTime == 0,
+			})
 		}
 	}
 	p.Articles = articles
@@ -87,3 +89,30 @@ func (c *Client) UniversityVideoPlayAuth(articleID, classID int) (response.V1Vid
 	}
 	return res, nil
 }
+
+// UniversityArticleInfo get university text lesson in the same shape as V1ArticleInfo, so that it
+// can be exported like articles of normal columns
+func (c *Client) UniversityArticleInfo(articleID, classID int) (response.V1ArticleResponse, error) {
+	var res response.V1ArticleResponse
+	var detail response.V1UniversityArticleResponse
+	r := c.newRequest(
+		resty.MethodPost,
+		GeekBangUniversityBaseURL,
+		UniversityV1ArticleInfoPath,
+		nil,
+		map[string]interface{}{
+			"article_id": articleID,
+			"class_id":   classID,
+		},
+		&detail,
+	)
+	if _, err := do(r); err != nil {
+		return res, err
+	}
+	res.Code = detail.Code
+	res.Data.ArticleTitle = detail.Data.ArticleTitle
+	res.Data.ArticleContent = detail.Data.ArticleContent
+	res.Data.ArticleCtime = detail.Data.ArticleCtime
+	res.Data.AuthorName = detail.Data.AuthorName
+	return res, nil
+}
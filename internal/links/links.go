@@ -0,0 +1,179 @@
+package links
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/nicoxiang/geektime-downloader/internal/geektime"
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/filenamify"
+)
+
+// ManifestFileName map article ID to course and title of every course downloaded to the folder
+const ManifestFileName = "articles.json"
+
+var articlePathRegexp = regexp.MustCompile(`^/column/article/(\d+)/?$`)
+
+// Target is article in local archive
+type Target struct {
+	// Course is course dir name under every output dir
+	Course string `json:"course"`
+	Title  string `json:"title"`
+	// Name is path of article files relative to course dir without extension, empty in manifest
+	// saved before path template
+	Name string `json:"name,omitempty"`
+	// Index is position of article in course starting from 1, zero in manifest saved before
+	// articles were ordered
+	Index int `json:"index,omitempty"`
+}
+
+// FileName return path of article files relative to course dir without extension
+func (t Target) FileName() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return filenamify.Filenamify(t.Title)
+}
+
+// Record is one link to geektime article found in downloaded article
+type Record struct {
+	Source string
+	URL    string
+	AID    int
+	Known  bool
+	Target Target
+}
+
+// Resolver resolve links to geektime articles against current course and courses downloaded
+// before, links are recorded to report the ones not downloaded yet. Methods are safe on nil
+// Resolver which resolves nothing.
+type Resolver struct {
+	course   geektime.Course
+	dirName  string
+	articles map[int]Target
+
+	mu      sync.Mutex
+	records map[string]Record
+}
+
+// ArticleID parse article ID of geektime article url, relative url like /column/article/1 is
+// supported
+func ArticleID(href string) (int, bool) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return 0, false
+	}
+	if u.Host != "" && u.Host != "time.geekbang.org" {
+		return 0, false
+	}
+	m := articlePathRegexp.FindStringSubmatch(u.Path)
+	if m == nil {
+		return 0, false
+	}
+	aid, err := strconv.Atoi(m[1])
+	return aid, err == nil
+}
+
+// NewResolver load manifest in root, articles of course take precedence
+func NewResolver(root string, course geektime.Course) *Resolver {
+	r := &Resolver{
+		course:   course,
+		dirName:  filenamify.Filenamify(course.Title),
+		articles: loadManifest(root),
+		records:  make(map[string]Record),
+	}
+	for i, a := range course.Articles {
+		r.articles[a.AID] = Target{Course: r.dirName, Title: a.Title, Name: a.FileName(), Index: i + 1}
+	}
+	return r
+}
+
+// CourseID return ID of course resolver created for
+func (r *Resolver) CourseID() int {
+	if r == nil {
+		return 0
+	}
+	return r.course.ID
+}
+
+// Resolve return local article of href linked in source article, ok is false if href is not a
+// geektime article link or the article is unknown
+func (r *Resolver) Resolve(source, href string) (target Target, sameCourse, ok bool) {
+	if r == nil {
+		return Target{}, false, false
+	}
+	aid, isArticle := ArticleID(href)
+	if !isArticle {
+		return Target{}, false, false
+	}
+	target, ok = r.articles[aid]
+
+	r.mu.Lock()
+	r.records[source+"\x00"+strconv.Itoa(aid)] = Record{Source: source, URL: href, AID: aid, Known: ok, Target: target}
+	r.mu.Unlock()
+	return target, ok && target.Course == r.dirName, ok
+}
+
+// Missing return recorded links whose target is unknown or not downloaded according to exists,
+// sorted by source article
+func (r *Resolver) Missing(exists func(Record) bool) []Record {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var missing []Record
+	for _, rec := range r.records {
+		if !rec.Known || !exists(rec) {
+			missing = append(missing, rec)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		if missing[i].Source != missing[j].Source {
+			return missing[i].Source < missing[j].Source
+		}
+		return missing[i].AID < missing[j].AID
+	})
+	return missing
+}
+
+// SaveManifest add articles of course to manifest in root
+func SaveManifest(root string, course geektime.Course) error {
+	articles := loadManifest(root)
+	dirName := filenamify.Filenamify(course.Title)
+	for i, a := range course.Articles {
+		articles[a.AID] = Target{Course: dirName, Title: a.Title, Name: a.FileName(), Index: i + 1}
+	}
+	data, err := json.Marshal(articles)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(root, ManifestFileName), data, 0644)
+}
+
+// CourseOrder return position in course of articles of course dir saved in manifest in root,
+// keyed by slash separated file name of article
+func CourseOrder(root, course string) map[string]int {
+	order := make(map[string]int)
+	for _, t := range loadManifest(root) {
+		if t.Course == course && t.Index > 0 {
+			order[filepath.ToSlash(t.FileName())] = t.Index
+		}
+	}
+	return order
+}
+
+func loadManifest(root string) map[int]Target {
+	articles := make(map[int]Target)
+	// missing or broken manifest only makes links to other courses unresolved
+	data, err := os.ReadFile(filepath.Join(root, ManifestFileName))
+	if err == nil {
+		_ = json.Unmarshal(data, &articles)
+	}
+	return articles
+}
@@ -0,0 +1,185 @@
+package markdown
+
+import (
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/JohannesKaufmann/html-to-markdown/plugin"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/files"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	// readEndRegexp match marker of free preview end, as comment or escaped text
+	readEndRegexp = regexp.MustCompile(`(?:<|&lt;)!--\s*\[\[\[read_end]]]\s*--(?:>|&gt;)`)
+	// languages used by geektime editor which mean no highlight
+	plainLanguages = map[string]bool{"plain": true, "plaintext": true, "text": true, "txt": true, "hljs": true}
+)
+
+// newConverter create converter tuned for geektime article html. Videos are linked to files
+// downloaded in videoDir relative to dir, remote url is used if video is not downloaded
+func newConverter(dir, videoDir string) *md.Converter {
+	conv := md.NewConverter("", true, nil)
+	conv.Use(plugin.GitHubFlavored())
+	conv.Before(promoteTableHeader)
+	conv.AddRules(codeBlockRule, mathScriptRule, mathMLRule, katexRule, videoRule(dir, videoDir))
+	return conv
+}
+
+// convert html of article to markdown
+func convert(articleHTML, dir, videoDir string) (string, error) {
+	return newConverter(dir, videoDir).ConvertString(readEndRegexp.ReplaceAllString(articleHTML, ""))
+}
+
+// promoteTableHeader turn cells of first row into header cells if table has no header, gfm
+// table requires a header row
+func promoteTableHeader(selec *goquery.Selection) {
+	selec.Find("table").Each(func(_ int, table *goquery.Selection) {
+		if table.Find("th").Length() > 0 {
+			return
+		}
+		table.Find("tr").First().Children().Each(func(_ int, cell *goquery.Selection) {
+			for _, n := range cell.Nodes {
+				n.Data = "th"
+				n.DataAtom = atom.Th
+			}
+		})
+	})
+}
+
+// codeBlockRule write fenced code with language taken from language-* or lang-* class or
+// data-language attribute of pre or code
+var codeBlockRule = md.Rule{
+	Filter: []string{"pre"},
+	Replacement: func(_ string, selec *goquery.Selection, opt *md.Options) *string {
+		code := selec.Find("code")
+		language := codeLanguage(code)
+		if language == "" {
+			language = codeLanguage(selec)
+		}
+
+		var content string
+		if code.Length() > 0 {
+			content = code.Text()
+		} else {
+			content = selec.Text()
+		}
+		content = strings.TrimSuffix(content, "\n")
+
+		fenceChar, _ := utf8.DecodeRuneInString(opt.Fence)
+		fence := md.CalculateCodeFence(fenceChar, content)
+		text := "\n\n" + fence + language + "\n" + content + "\n" + fence + "\n\n"
+		return &text
+	},
+}
+
+func codeLanguage(selec *goquery.Selection) string {
+	if selec.Length() == 0 {
+		return ""
+	}
+	language := selec.AttrOr("data-language", "")
+	if language == "" {
+		for _, class := range strings.Fields(selec.AttrOr("class", "")) {
+			if l, ok := strings.CutPrefix(class, "language-"); ok {
+				language = l
+				break
+			}
+			if l, ok := strings.CutPrefix(class, "lang-"); ok {
+				language = l
+				break
+			}
+		}
+	}
+	language = strings.ToLower(strings.TrimSpace(language))
+	if plainLanguages[language] {
+		return ""
+	}
+	return language
+}
+
+// mathScriptRule keep tex source of MathJax script
+var mathScriptRule = md.Rule{
+	Filter: []string{"script"},
+	Replacement: func(_ string, selec *goquery.Selection, _ *md.Options) *string {
+		t := selec.AttrOr("type", "")
+		if !strings.HasPrefix(t, "math/tex") {
+			return nil
+		}
+		return mathText(selec.Text(), strings.Contains(t, "mode=display"))
+	},
+}
+
+// mathMLRule keep tex annotation or alttext of MathML
+var mathMLRule = md.Rule{
+	Filter: []string{"math"},
+	Replacement: func(_ string, selec *goquery.Selection, _ *md.Options) *string {
+		tex := selec.Find(`annotation[encoding="application/x-tex"]`).Text()
+		if tex == "" {
+			tex = selec.AttrOr("alttext", "")
+		}
+		if tex == "" {
+			return nil
+		}
+		return mathText(tex, selec.AttrOr("display", "") == "block")
+	},
+}
+
+// katexRule keep tex annotation of KaTeX rendered html, rendered html is dropped
+var katexRule = md.Rule{
+	Filter: []string{"span"},
+	Replacement: func(_ string, selec *goquery.Selection, _ *md.Options) *string {
+		display := selec.HasClass("katex-display")
+		if !display && !selec.HasClass("katex") {
+			return nil
+		}
+		tex := selec.Find(`annotation[encoding="application/x-tex"]`).First().Text()
+		if tex == "" {
+			return nil
+		}
+		return mathText(tex, display)
+	},
+}
+
+func mathText(tex string, display bool) *string {
+	tex = strings.TrimSpace(tex)
+	if display {
+		text := "\n\n$$\n" + tex + "\n$$\n\n"
+		return &text
+	}
+	text := "$" + tex + "$"
+	return &text
+}
+
+// videoRule convert video to link of file downloaded in videoDir, files are named after url path
+// like video.DownloadMP4 does, remote url is kept if video is not downloaded
+func videoRule(dir, videoDir string) md.Rule {
+	return md.Rule{
+		Filter: []string{"video"},
+		Replacement: func(_ string, selec *goquery.Selection, _ *md.Options) *string {
+			src := selec.AttrOr("src", "")
+			if src == "" {
+				src = selec.Find("source[src]").AttrOr("src", "")
+			}
+			u, err := url.Parse(src)
+			if src == "" || err != nil {
+				return nil
+			}
+			name := path.Base(u.Path)
+			href := src
+			local := filepath.Join(videoDir, name)
+			if videoDir != "" && files.CheckFileExists(local) {
+				if rel, err := filepath.Rel(dir, local); err == nil {
+					href = linkDestination(filepath.ToSlash(rel))
+				}
+			}
+			text := "\n\n[视频: " + escapeLinkText(name) + "](" + href + ")\n\n"
+			return &text
+		},
+	}
+}
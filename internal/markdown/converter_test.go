@@ -0,0 +1,44 @@
+package markdown
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	dir := t.TempDir()
+	videoDir := filepath.Join(dir, "videos", "article")
+	if err := os.MkdirAll(videoDir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(videoDir, "local.mp4"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	articleDir := filepath.Join(dir, "markdown")
+
+	tests := []struct {
+		name, html, want string
+	}{
+		{"read end", `<p>a</p><!-- [[[read_end]]] --><p>&lt;!-- [[[read_end]]] --&gt;b</p>`, "a\n\nb"},
+		{"table without header", `<table><tr><td>a</td><td>b</td></tr><tr><td>1</td><td>2</td></tr></table>`, "| a | b |\n| --- | --- |\n| 1 | 2 |"},
+		{"code language", `<pre class="hljs"><code class="hljs language-go">x := 1
+</code></pre>`, "```go\nx := 1\n```"},
+		{"plain code", `<pre><code class="plain">x</code></pre>`, "```\nx\n```"},
+		{"local video", `<video><source src="https://media001.geekbang.org/local.mp4"></video>`, "[视频: local.mp4](../videos/article/local.mp4)"},
+		{"remote video", `<video src="https://media001.geekbang.org/remote.mp4"></video>`, "[视频: remote.mp4](https://media001.geekbang.org/remote.mp4)"},
+		{"katex", `<p>x <span class="katex"><span class="katex-mathml"><math><semantics><annotation encoding="application/x-tex">a^2</annotation></semantics></math></span><span class="katex-html">a2</span></span></p>`, "x $a^2$"},
+		{"katex display", `<span class="katex-display"><span class="katex"><math><semantics><annotation encoding="application/x-tex">\sum x</annotation></semantics></math></span></span>`, "$$\n\\sum x\n$$"},
+		{"mathjax", `<p><script type="math/tex">y</script></p>`, "$y$"},
+	}
+	for _, tt := range tests {
+		got, err := convert(tt.html, articleDir, videoDir)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if strings.TrimSpace(got) != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
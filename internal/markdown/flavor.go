@@ -0,0 +1,221 @@
+package markdown
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nicoxiang/geektime-downloader/internal/geektime"
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/files"
+)
+
+// Flavor decide file layout, image links and course level files of generated markdown
+type Flavor string
+
+const (
+	// FlavorDefault write <title>.md with images shared by course in images
+	FlavorDefault Flavor = "default"
+	// FlavorObsidian save images in course attachments folder and embed them with wikilinks
+	FlavorObsidian Flavor = "obsidian"
+	// FlavorHugo write every article as page bundle <title>/index.md, course as section _index.md
+	FlavorHugo Flavor = "hugo"
+	// FlavorMkDocs write articles in docs and generate mkdocs.yml nav
+	FlavorMkDocs Flavor = "mkdocs"
+	// FlavorDocusaurus write articles in docs and generate sidebars.js
+	FlavorDocusaurus Flavor = "docusaurus"
+
+	attachmentsDir      = "attachments"
+	docsDir             = "docs"
+	hugoSectionFileName = "_index" + MDExtension
+)
+
+// image links to attachments relative to article, articles nested in sub dirs of course link to
+// ../attachments
+var attachmentRegexp = regexp.MustCompile(`!\[[^\]]*]\((?:\.\./)*` + attachmentsDir + `/([^)\s]+)(?:\s+"[^"]*")?\)`)
+
+var (
+	// number prefix of folder like "01-", which docusaurus strips from doc id
+	numberPrefixRegexp = regexp.MustCompile(`^\d+\s*[-_.]+\s*([^-_.\s].*)$`)
+	// date like prefix is not a number prefix
+	datePrefixRegexp = regexp.MustCompile(`^\d+[-_.]\d+`)
+)
+
+// ParseFlavor ...
+func ParseFlavor(s string) (Flavor, error) {
+	switch f := Flavor(strings.ToLower(strings.TrimSpace(s))); f {
+	case "", FlavorDefault:
+		return FlavorDefault, nil
+	case FlavorObsidian, FlavorHugo, FlavorMkDocs, FlavorDocusaurus:
+		return f, nil
+	}
+	return "", fmt.Errorf("不支持的 Markdown 格式: %s", s)
+}
+
+// NeedsFrontMatter report whether flavor depends on front matter for title and order
+func (f Flavor) NeedsFrontMatter() bool {
+	return f == FlavorHugo || f == FlavorDocusaurus
+}
+
+// ArticlePath return markdown file of article in course dir, name is path of article files
+// relative to course dir without extension
+func (f Flavor) ArticlePath(dir, name string) string {
+	switch f {
+	case FlavorHugo:
+		return filepath.Join(dir, name, "index"+MDExtension)
+	case FlavorMkDocs, FlavorDocusaurus:
+		return filepath.Join(dir, docsDir, name+MDExtension)
+	}
+	return filepath.Join(dir, name+MDExtension)
+}
+
+// ArticleExists check whether markdown of article was saved or recorded as without content before
+func (f Flavor) ArticleExists(dir, name string) bool {
+	return files.CheckFileExists(f.ArticlePath(dir, name)) ||
+		files.CheckFileExists(filepath.Join(dir, name+NoContentExtension))
+}
+
+// imageDir return dir where images are saved, dir is where the markdown file is. Images are
+// shared by course except hugo, whose page bundles can only use their own resources
+func (f Flavor) imageDir(courseDir, dir string) string {
+	switch f {
+	case FlavorObsidian:
+		return filepath.Join(courseDir, attachmentsDir)
+	case FlavorHugo, FlavorMkDocs, FlavorDocusaurus:
+		return filepath.Join(dir, "images")
+	}
+	return filepath.Join(courseDir, "images")
+}
+
+// rewriteImages convert markdown images to wikilink embeds for obsidian
+func (f Flavor) rewriteImages(md string) string {
+	if f != FlavorObsidian {
+		return md
+	}
+	return attachmentRegexp.ReplaceAllString(md, "![[$1]]")
+}
+
+// frontMatterFields return flavor specific front matter used to order articles
+func (f Flavor) frontMatterFields(fm FrontMatter) string {
+	switch f {
+	case FlavorHugo:
+		if fm.Position > 0 {
+			return "weight: " + strconv.Itoa(fm.Position) + "\n"
+		}
+	case FlavorDocusaurus:
+		s := "id: " + docID(fm.ArticleID) + "\n"
+		if fm.Position > 0 {
+			s += "sidebar_position: " + strconv.Itoa(fm.Position) + "\n"
+		}
+		return s
+	}
+	return ""
+}
+
+func docID(aid int) string {
+	return "article-" + strconv.Itoa(aid)
+}
+
+// sidebarDocID return id of article used in sidebar, docusaurus prefixes id in front matter with
+// folder of doc relative to docs, number prefixes of folders removed
+func sidebarDocID(a geektime.Article) string {
+	var segments []string
+	if dir := filepath.ToSlash(filepath.Dir(a.FileName())); dir != "." {
+		for _, segment := range strings.Split(dir, "/") {
+			segments = append(segments, stripNumberPrefix(segment))
+		}
+	}
+	return strings.Join(append(segments, docID(a.AID)), "/")
+}
+
+func stripNumberPrefix(s string) string {
+	if datePrefixRegexp.MatchString(s) {
+		return s
+	}
+	if m := numberPrefixRegexp.FindStringSubmatch(s); m != nil {
+		return m[1]
+	}
+	return s
+}
+
+// WriteCourseFiles write course README.md and files required by flavor, only downloaded articles
+// are included in navigation
+func WriteCourseFiles(course geektime.Course, dir string, f Flavor) error {
+	if err := writeCourseReadme(course, dir, f); err != nil {
+		return err
+	}
+	switch f {
+	case FlavorMkDocs:
+		return os.WriteFile(filepath.Join(dir, "mkdocs.yml"), []byte(mkdocsConfig(course, dir)), 0644)
+	case FlavorDocusaurus:
+		return os.WriteFile(filepath.Join(dir, "sidebars.js"), []byte(docusaurusSidebars(course, dir)), 0644)
+	}
+	return nil
+}
+
+type navSection struct {
+	title    string
+	articles []geektime.Article
+}
+
+// downloadedSections group downloaded articles by section in course order
+func downloadedSections(course geektime.Course, dir string, f Flavor) []navSection {
+	var sections []navSection
+	for _, a := range course.Articles {
+		if !files.CheckFileExists(f.ArticlePath(dir, a.FileName())) {
+			continue
+		}
+		if len(sections) == 0 || sections[len(sections)-1].title != a.SectionTitle {
+			sections = append(sections, navSection{title: a.SectionTitle})
+		}
+		s := &sections[len(sections)-1]
+		s.articles = append(s.articles, a)
+	}
+	return sections
+}
+
+func mkdocsConfig(course geektime.Course, dir string) string {
+	var sb strings.Builder
+	sb.WriteString("site_name: " + strconv.Quote(course.Title) + "\n")
+	if course.Author != "" {
+		sb.WriteString("site_author: " + strconv.Quote(course.Author) + "\n")
+	}
+	sb.WriteString("docs_dir: " + docsDir + "\n")
+
+	sb.WriteString("nav:\n")
+	for _, s := range downloadedSections(course, dir, FlavorMkDocs) {
+		indent := "  "
+		if s.title != "" {
+			sb.WriteString("  - " + strconv.Quote(s.title) + ":\n")
+			indent = "      "
+		}
+		for _, a := range s.articles {
+			sb.WriteString(indent + "- " + strconv.Quote(a.Title) + ": " +
+				strconv.Quote(filepath.ToSlash(a.FileName())+MDExtension) + "\n")
+		}
+	}
+	return sb.String()
+}
+
+func docusaurusSidebars(course geektime.Course, dir string) string {
+	var sb strings.Builder
+	sb.WriteString("// @ts-check\n\n/** @type {import('@docusaurus/plugin-content-docs').SidebarsConfig} */\n")
+	sb.WriteString("const sidebars = {\n  course: [\n")
+	for _, s := range downloadedSections(course, dir, FlavorDocusaurus) {
+		indent := "    "
+		if s.title != "" {
+			sb.WriteString("    {\n      type: 'category',\n      label: " + strconv.Quote(s.title) + ",\n      items: [\n")
+			indent = "        "
+		}
+		for _, a := range s.articles {
+			sb.WriteString(indent + strconv.Quote(sidebarDocID(a)) + ",\n")
+		}
+		if s.title != "" {
+			sb.WriteString("      ],\n    },\n")
+		}
+	}
+	sb.WriteString("  ],\n};\n\nmodule.exports = sidebars;\n")
+	return sb.String()
+}
@@ -0,0 +1,23 @@
+package markdown
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/nicoxiang/geektime-downloader/internal/geektime"
+)
+
+func TestSidebarDocID(t *testing.T) {
+	for name, want := range map[string]string{
+		"001-开篇词": "article-1",
+		filepath.Join("01-基础篇", "002-入门"):     "基础篇/article-1",
+		filepath.Join("1 . 基础篇", "入门"):        "基础篇/article-1",
+		filepath.Join("基础篇", "入门"):            "基础篇/article-1",
+		filepath.Join("2021-01-回顾", "入门"):     "2021-01-回顾/article-1",
+		filepath.Join("01-进阶", "02-案例", "入门"): "进阶/案例/article-1",
+	} {
+		if got := sidebarDocID(geektime.Article{AID: 1, Name: name}); got != want {
+			t.Errorf("sidebarDocID(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
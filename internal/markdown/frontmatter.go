@@ -0,0 +1,81 @@
+package markdown
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+const frontMatterDelimiter = "---"
+
+// FrontMatter is YAML metadata written at the top of article markdown, zero values are omitted
+type FrontMatter struct {
+	Title     string
+	ArticleID int
+	CourseID  int
+	Course    string
+	Chapter   string
+	Author    string
+	Date      time.Time
+	Source    string
+	// Audio is path of article audio relative to markdown file
+	Audio string
+	Tags  []string
+	// Position is 1 based article order in course, used by flavors which order pages by front matter
+	Position int
+}
+
+// String render front matter as YAML block including delimiters
+func (fm FrontMatter) String() string {
+	return fm.render(FlavorDefault)
+}
+
+func (fm FrontMatter) render(f Flavor) string {
+	var sb strings.Builder
+	sb.WriteString(frontMatterDelimiter + "\n")
+	writeString := func(key, value string) {
+		if value != "" {
+			sb.WriteString(key + ": " + strconv.Quote(value) + "\n")
+		}
+	}
+	writeInt := func(key string, value int) {
+		if value != 0 {
+			sb.WriteString(key + ": " + strconv.Itoa(value) + "\n")
+		}
+	}
+	writeString("title", fm.Title)
+	writeInt("article_id", fm.ArticleID)
+	writeInt("course_id", fm.CourseID)
+	writeString("course", fm.Course)
+	writeString("chapter", fm.Chapter)
+	writeString("author", fm.Author)
+	if !fm.Date.IsZero() {
+		sb.WriteString("date: " + fm.Date.Format(time.RFC3339) + "\n")
+	}
+	writeString("source", fm.Source)
+	writeString("audio", fm.Audio)
+	if len(fm.Tags) > 0 {
+		sb.WriteString("tags:\n")
+		for _, t := range fm.Tags {
+			sb.WriteString("  - " + strconv.Quote(t) + "\n")
+		}
+	}
+	sb.WriteString(f.frontMatterFields(fm))
+	sb.WriteString(frontMatterDelimiter + "\n")
+	return sb.String()
+}
+
+// StripFrontMatter remove leading YAML front matter of markdown content if any
+func StripFrontMatter(content string) string {
+	if !strings.HasPrefix(content, frontMatterDelimiter+"\n") {
+		return content
+	}
+	rest := content[len(frontMatterDelimiter)+1:]
+	if strings.HasPrefix(rest, frontMatterDelimiter+"\n") {
+		return rest[len(frontMatterDelimiter)+1:]
+	}
+	if i := strings.Index(rest, "\n"+frontMatterDelimiter+"\n"); i >= 0 {
+		return rest[i+len(frontMatterDelimiter)+2:]
+	}
+	return content
+}
@@ -2,77 +2,100 @@ package markdown
 
 import (
 	"context"
-	"errors"
 	"net/url"
 	"os"
-	"path"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
-	"sync"
 
-	md "github.com/JohannesKaufmann/html-to-markdown"
 	"github.com/nicoxiang/geektime-downloader/internal/geektime"
-	"github.com/nicoxiang/geektime-downloader/internal/pkg/downloader"
-	"github.com/nicoxiang/geektime-downloader/internal/pkg/filenamify"
+	"github.com/nicoxiang/geektime-downloader/internal/links"
 	"github.com/nicoxiang/geektime-downloader/internal/pkg/files"
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/images"
 )
 
 var (
-	converter *md.Converter
-	imgRegexp = regexp.MustCompile(`!\[(.*?)]\((.*?)\)`)
+	// image with optional title, which is common in native markdown
+	imgRegexp = regexp.MustCompile(`!\[(.*?)]\((\S*?)(\s+"[^"]*")?\)`)
+	// links to geektime articles, absolute or site relative
+	articleLinkRegexp = regexp.MustCompile(`\[([^\]]*)]\(((?:https?://time\.geekbang\.org)?/column/article/\d+[^)\s]*)\)`)
 )
 
-// MDExtension ...
-const MDExtension = ".md"
+const (
+	// MDExtension ...
+	MDExtension = ".md"
+	// NoContentExtension is extension of marker file recording article without content
+	NoContentExtension = ".nocontent"
+)
 
-type markdownString struct {
-	sync.Mutex
-	s string
+// MarkNoContent record article without content in course dir, so later runs don't fetch its info
+// again
+func MarkNoContent(dir, name string) error {
+	dst := filepath.Join(dir, name+NoContentExtension)
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, nil, 0644)
 }
 
-func (ms *markdownString) ReplaceAll(o, n string) {
-	ms.Lock()
-	defer ms.Unlock()
-	ms.s = strings.ReplaceAll(ms.s, o, n)
+// DownloadOptions is article content and output settings of Download
+type DownloadOptions struct {
+	// HTML is converted to markdown if NativeMD is empty
+	HTML     string
+	NativeMD string
+	Title    string
+	// Name is path of article relative to Dir without extension
+	Name string
+	// Dir is course dir
+	Dir string
+	// VideoDir is where videos of article are downloaded, videos in html are linked to it
+	VideoDir string
+	// Comments are appended to the end of article if not empty
+	Comments []geektime.Comment
+	// FrontMatter is written before title if not nil
+	FrontMatter *FrontMatter
+	// Flavor decide file layout and image links, empty is FlavorDefault
+	Flavor Flavor
+	// Resolver rewrite links to articles of this course and downloaded articles of other courses
+	// to relative links, nil keeps online links
+	Resolver  *links.Resolver
+	Overwrite bool
 }
 
-// Download article as markdown
-func Download(ctx context.Context, html, title, dir string, aid int, overwrite bool) (bool, error) {
+// Download article as markdown, native markdown is used if not empty and html is converted
+// otherwise
+func Download(ctx context.Context, opts DownloadOptions) (bool, error) {
 	select {
 	case <-ctx.Done():
 		return false, context.Canceled
 	default:
 	}
 
-	fullName := path.Join(dir, filenamify.Filenamify(title)+MDExtension)
-	if files.CheckFileExists(fullName) && !overwrite {
+	fullName := opts.Flavor.ArticlePath(opts.Dir, opts.Name)
+	if files.CheckFileExists(fullName) && !opts.Overwrite {
 		return true, nil
 	}
+	articleDir := filepath.Dir(fullName)
 
 	// step1: convert to md string
-	markdown, err := getDefaultConverter().ConvertString(html)
-	if err != nil {
-		return false, err
+	var err error
+	markdown := readEndRegexp.ReplaceAllString(opts.NativeMD, "")
+	if strings.TrimSpace(markdown) == "" {
+		markdown, err = convert(opts.HTML, articleDir, opts.VideoDir)
+		if err != nil {
+			return false, err
+		}
 	}
 	// step2: download images
-	var ss = &markdownString{s: markdown}
-	imageURLs := findAllImages(markdown)
-
-	// images/aid/imageName.png
-	imagesFolder := filepath.Join(dir, "images", strconv.Itoa(aid))
-
-	if _, err := os.Stat(imagesFolder); errors.Is(err, os.ErrNotExist) {
-		os.MkdirAll(imagesFolder, os.ModePerm)
-	}
-
-	err = writeImageFile(ctx, imageURLs, dir, imagesFolder, ss)
-
+	markdown, err = localizeImages(ctx, markdown, opts.Flavor.imageDir(opts.Dir, articleDir), articleDir)
 	if err != nil {
 		return false, err
 	}
 
+	if err := os.MkdirAll(articleDir, os.ModePerm); err != nil {
+		return false, err
+	}
 	f, err := os.Create(fullName)
 	defer func() {
 		_ = f.Close()
@@ -81,81 +104,108 @@ func Download(ctx context.Context, html, title, dir string, aid int, overwrite b
 		return false, err
 	}
 	// step3: write md file
-	_, err = f.WriteString("# " + title + "\n" + ss.s)
+	var header string
+	if opts.FrontMatter != nil {
+		header = opts.FrontMatter.render(opts.Flavor)
+	}
+	content := rewriteArticleLinks(opts.Flavor.rewriteImages(markdown), opts.Title, opts.Dir, articleDir, opts.Flavor, opts.Resolver)
+	_, err = f.WriteString(header + "# " + opts.Title + "\n" + content + commentsMarkdown(opts.Comments))
 	if err != nil {
 		return false, err
 	}
 	return false, nil
 }
 
-func findAllImages(md string) (images []string) {
-	for _, matches := range imgRegexp.FindAllStringSubmatch(md, -1) {
-		if len(matches) == 3 {
-			s := matches[2]
-			isImg, err := isImageURL(s)
-			if err == nil && isImg {
-				images = append(images, s)
-			}
-			// sometime exists broken image url, just ignore
+// rewriteArticleLinks rewrite links to geektime articles to local markdown files, dir is course
+// dir and articleDir is where the markdown file is
+func rewriteArticleLinks(md, title, dir, articleDir string, flavor Flavor, resolver *links.Resolver) string {
+	return articleLinkRegexp.ReplaceAllStringFunc(md, func(s string) string {
+		m := articleLinkRegexp.FindStringSubmatch(s)
+		text, href := m[1], m[2]
+		online := s
+		if strings.HasPrefix(href, "/") {
+			online = "[" + text + "](" + geektime.DefaultBaseURL + href + ")"
 		}
-	}
-	return
+		target, sameCourse, ok := resolver.Resolve(title, href)
+		if !ok {
+			return online
+		}
+		fileName := flavor.ArticlePath(filepath.Join(filepath.Dir(dir), target.Course), target.FileName())
+		if !sameCourse && !files.CheckFileExists(fileName) {
+			return online
+		}
+		if flavor == FlavorObsidian {
+			return "[[" + strings.TrimSuffix(filepath.Base(fileName), MDExtension) + "|" + text + "]]"
+		}
+		rel, err := filepath.Rel(articleDir, fileName)
+		if err != nil {
+			return online
+		}
+		return "[" + text + "](" + linkDestination(filepath.ToSlash(rel)) + ")"
+	})
 }
 
-func getDefaultConverter() *md.Converter {
-	if converter == nil {
-		converter = md.NewConverter("", true, nil)
+// commentsMarkdown render comments as a markdown section, replies are quoted under comment
+func commentsMarkdown(comments []geektime.Comment) string {
+	if len(comments) == 0 {
+		return ""
 	}
-	return converter
-}
-
-func writeImageFile(ctx context.Context,
-	imageURLs []string,
-	dir,
-	imagesFolder string,
-	ms *markdownString,
-) (err error) {
-	for _, imageURL := range imageURLs {
-		segments := strings.Split(imageURL, "/")
-		f := segments[len(segments)-1]
-		if i := strings.Index(f, "?"); i > 0 {
-			f = f[:i]
+	var sb strings.Builder
+	sb.WriteString("\n\n## 精选留言\n")
+	for _, c := range comments {
+		sb.WriteString("\n**" + c.DisplayName() + "** · " + c.Date())
+		if c.LikeCount > 0 {
+			sb.WriteString(" · 赞 " + strconv.Itoa(c.LikeCount))
 		}
-		imageLocalFullPath := filepath.Join(imagesFolder, f)
-
-		headers := make(map[string]string, 2)
-		headers[geektime.Origin] = geektime.DefaultBaseURL
-		headers[geektime.UserAgent] = geektime.DefaultUserAgent
-
-		_, err := downloader.DownloadFileConcurrently(ctx, imageLocalFullPath, imageURL, headers, 1)
-
-		if err != nil {
-			return err
+		sb.WriteString("\n\n" + strings.TrimSpace(c.Content) + "\n")
+		for _, r := range c.Replies {
+			sb.WriteString("\n> **" + r.DisplayName() + "** · " + r.Date() + "\n>\n")
+			for _, line := range strings.Split(strings.TrimSpace(r.Content), "\n") {
+				sb.WriteString("> " + line + "\n")
+			}
 		}
+	}
+	return sb.String()
+}
 
-		rel, _ := filepath.Rel(dir, imageLocalFullPath)
-		ms.ReplaceAll(imageURL, filepath.ToSlash(rel))
+func findAllImages(md string) (urls []string) {
+	for _, matches := range imgRegexp.FindAllStringSubmatch(md, -1) {
+		if len(matches) == 4 {
+			if s := matches[2]; isRemoteURL(s) {
+				urls = append(urls, s)
+			}
+		}
 	}
-	return nil
+	return
 }
 
-func isImageURL(urlStr string) (bool, error) {
-	// 解析 URL
-	parsedURL, err := url.ParseRequestURI(urlStr)
+// localizeImages download images to imageDir and replace urls with path relative to dir
+func localizeImages(ctx context.Context, md, imageDir, dir string) (string, error) {
+	imageURLs := findAllImages(md)
+	if len(imageURLs) == 0 {
+		return md, nil
+	}
+	names, err := images.NewFetcher(imageDir).Fetch(ctx, imageURLs)
 	if err != nil {
-		return false, err
+		return "", err
 	}
-	// 提取路径部分
-	filePath := parsedURL.Path
-
-	// 获取文件扩展名并转换为小写
-	ext := strings.ToLower(path.Ext(filePath))
+	return imgRegexp.ReplaceAllStringFunc(md, func(s string) string {
+		m := imgRegexp.FindStringSubmatch(s)
+		name, ok := names[m[2]]
+		if !ok {
+			return s
+		}
+		rel, err := filepath.Rel(dir, filepath.Join(imageDir, name))
+		if err != nil {
+			return s
+		}
+		return "![" + m[1] + "](" + filepath.ToSlash(rel) + m[3] + ")"
+	}), nil
+}
 
-	// 检查扩展名是否属于图片类型
-	switch ext {
-	case ".jpg", ".jpeg", ".png", ".gif", ".webp", ".bmp", ".tiff":
-		return true, nil
-	default:
-		return false, nil
-	}
+// isRemoteURL report whether image url should be downloaded, format of image is sniffed from
+// content as some urls have no extension
+func isRemoteURL(urlStr string) bool {
+	u, err := url.ParseRequestURI(urlStr)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
 }
@@ -0,0 +1,98 @@
+package markdown
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nicoxiang/geektime-downloader/internal/geektime"
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/files"
+)
+
+// ReadmeFileName is course index written in markdown course dir
+const ReadmeFileName = "README" + MDExtension
+
+// courseReadme render course metadata, author intro and table of contents in course order,
+// articles not downloaded yet are listed without link
+func courseReadme(course geektime.Course, dir string, f Flavor) string {
+	var sb strings.Builder
+	var toc strings.Builder
+	downloaded := 0
+	section := ""
+	for i, a := range course.Articles {
+		if i == 0 || a.SectionTitle != section {
+			section = a.SectionTitle
+			if section != "" {
+				toc.WriteString("\n### " + section + "\n\n")
+			} else if i > 0 {
+				toc.WriteString("\n")
+			}
+		}
+		fileName := f.ArticlePath(dir, a.FileName())
+		if !files.CheckFileExists(fileName) {
+			toc.WriteString(strconv.Itoa(i+1) + ". " + a.Title + "\n")
+			continue
+		}
+		downloaded++
+		rel, _ := filepath.Rel(dir, fileName)
+		toc.WriteString(strconv.Itoa(i+1) + ". [" + escapeLinkText(a.Title) + "](" + linkDestination(filepath.ToSlash(rel)) + ")\n")
+	}
+
+	if f != FlavorHugo {
+		sb.WriteString("# " + course.Title + "\n\n")
+	}
+	if course.Subtitle != "" {
+		sb.WriteString("> " + course.Subtitle + "\n\n")
+	}
+	if course.Cover != "" {
+		sb.WriteString("![" + escapeLinkText(course.Title) + "](" + course.Cover + ")\n\n")
+	}
+	if course.Author != "" {
+		sb.WriteString("- 作者: " + course.Author)
+		if course.AuthorIntro != "" {
+			sb.WriteString(", " + course.AuthorIntro)
+		}
+		sb.WriteString("\n")
+	}
+	if course.ID != 0 {
+		sb.WriteString("- 课程链接: " + geektime.DefaultBaseURL + "/column/intro/" + strconv.Itoa(course.ID) + "\n")
+	}
+	sb.WriteString("- 已下载: " + strconv.Itoa(downloaded) + " / " + strconv.Itoa(len(course.Articles)) + " 篇\n")
+	sb.WriteString("- 更新时间: " + time.Now().Format("2006-01-02 15:04") + "\n")
+	if brief := strings.TrimSpace(course.AuthorBrief); brief != "" {
+		sb.WriteString("\n## 作者简介\n\n" + brief + "\n")
+	}
+	sb.WriteString("\n## 目录\n" + toc.String())
+	return sb.String()
+}
+
+// writeCourseReadme write README.md of course, hugo use it as content of section _index.md
+func writeCourseReadme(course geektime.Course, dir string, f Flavor) error {
+	content := courseReadme(course, dir, f)
+	fileName := filepath.Join(dir, ReadmeFileName)
+	if f == FlavorHugo {
+		fileName = filepath.Join(dir, hugoSectionFileName)
+		content = FrontMatter{Title: course.Title, Author: course.Author}.String() + content
+	}
+	return os.WriteFile(fileName, []byte(content), 0644)
+}
+
+// IsCourseIndex report whether markdown file name is course index rather than article
+func IsCourseIndex(name string) bool {
+	return name == ReadmeFileName || name == hugoSectionFileName
+}
+
+func escapeLinkText(s string) string {
+	return strings.NewReplacer("[", `\[`, "]", `\]`).Replace(s)
+}
+
+// linkDestination wrap destination in angle brackets if it contains characters which end a
+// markdown link
+func linkDestination(s string) string {
+	if strings.ContainsAny(s, " ()") {
+		return "<" + s + ">"
+	}
+	return s
+}
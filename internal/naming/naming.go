@@ -0,0 +1,206 @@
+package naming
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nicoxiang/geektime-downloader/internal/geektime"
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/filenamify"
+)
+
+// DefaultTemplate nest articles by numbered chapter and prefix them by index, so that files sort
+// in course order. Articles of courses without chapter stay directly in course dir
+const DefaultTemplate = "{course}/{chapter_index:02}-{chapter}/{index:03}-{title}.{ext}"
+
+const (
+	fieldCourse       = "course"
+	fieldChapter      = "chapter"
+	fieldChapterIndex = "chapter_index"
+	fieldIndex        = "index"
+	fieldTitle        = "title"
+	fieldID           = "id"
+	fieldExt          = "ext"
+
+	courseSegment = "{" + fieldCourse + "}"
+	extSuffix     = ".{" + fieldExt + "}"
+)
+
+var (
+	placeholderRegexp = regexp.MustCompile(`\{([a-z_]+)(?::([^{}]*))?}`)
+	widthRegexp       = regexp.MustCompile(`^0[1-9]$`)
+)
+
+// Template decide path of article files under output dir of each format, e.g.
+// {course}/{chapter_index:02}-{chapter}/{index:03}-{title}.{ext}. Dir segments using chapter are
+// omitted for articles without chapter.
+type Template struct {
+	raw string
+	// segments after {course}, extension excluded
+	segments [][]token
+}
+
+type token struct {
+	text  string
+	field string
+	// zero padded width of number fields
+	width int
+}
+
+// Parse parse template, empty string means DefaultTemplate
+func Parse(s string) (Template, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		s = DefaultTemplate
+	}
+	t := Template{raw: s}
+	rest, ok := strings.CutPrefix(s, courseSegment+"/")
+	if !ok {
+		return t, fmt.Errorf("路径模板需以 %s/ 开头: %s", courseSegment, s)
+	}
+	rest, ok = strings.CutSuffix(rest, extSuffix)
+	if !ok {
+		return t, fmt.Errorf("路径模板需以 %s 结尾: %s", extSuffix, s)
+	}
+	parts := strings.Split(rest, "/")
+	for i, part := range parts {
+		if strings.TrimSpace(part) == "" || part == "." || part == ".." {
+			return t, fmt.Errorf("路径模板包含无效的目录: %s", s)
+		}
+		segment, err := parseSegment(part)
+		if err != nil {
+			return t, fmt.Errorf("路径模板 %s 无效: %w", s, err)
+		}
+		if i == len(parts)-1 && !hasField(segment, fieldTitle) && !hasField(segment, fieldID) {
+			return t, fmt.Errorf("路径模板的文件名需包含 {%s} 或 {%s}: %s", fieldTitle, fieldID, s)
+		}
+		t.segments = append(t.segments, segment)
+	}
+	return t, nil
+}
+
+func parseSegment(s string) ([]token, error) {
+	var tokens []token
+	last := 0
+	for _, m := range placeholderRegexp.FindAllStringSubmatchIndex(s, -1) {
+		if m[0] > last {
+			tokens = append(tokens, token{text: s[last:m[0]]})
+		}
+		last = m[1]
+		tok := token{field: s[m[2]:m[3]]}
+		switch tok.field {
+		case fieldChapter, fieldTitle:
+		case fieldIndex, fieldChapterIndex, fieldID:
+			if m[4] >= 0 {
+				spec := s[m[4]:m[5]]
+				if !widthRegexp.MatchString(spec) {
+					return nil, fmt.Errorf("不支持的格式 {%s:%s}, 如需补零请使用 {%s:03}", tok.field, spec, tok.field)
+				}
+				tok.width, _ = strconv.Atoi(spec)
+			}
+		case fieldCourse, fieldExt:
+			return nil, fmt.Errorf("{%s} 只能用于模板开头或结尾", tok.field)
+		default:
+			return nil, fmt.Errorf("不支持的占位符 {%s}", tok.field)
+		}
+		if m[4] >= 0 && tok.width == 0 {
+			return nil, fmt.Errorf("{%s} 不支持格式", tok.field)
+		}
+		tokens = append(tokens, tok)
+	}
+	if last < len(s) {
+		tokens = append(tokens, token{text: s[last:]})
+	}
+	for _, tok := range tokens {
+		if tok.field == "" && strings.ContainsAny(tok.text, "{}") {
+			return nil, fmt.Errorf("无法解析 %s", s)
+		}
+	}
+	return tokens, nil
+}
+
+func hasField(tokens []token, fields ...string) bool {
+	for _, tok := range tokens {
+		for _, f := range fields {
+			if tok.field == f {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// String ...
+func (t Template) String() string {
+	return t.raw
+}
+
+// Apply return copy of course whose articles are named by template in course order. Names which
+// collide with names of articles before, ignoring case, get article ID appended, and a counter
+// too if that is still taken.
+func (t Template) Apply(course geektime.Course) geektime.Course {
+	articles := make([]geektime.Article, len(course.Articles))
+	chapters := make(map[string]int)
+	used := make(map[string]bool, len(articles))
+	for i, a := range course.Articles {
+		if a.SectionTitle != "" && chapters[a.SectionTitle] == 0 {
+			chapters[a.SectionTitle] = len(chapters) + 1
+		}
+		name := t.name(a, i+1, chapters[a.SectionTitle])
+		for base, n := name, 1; used[strings.ToLower(name)]; n++ {
+			// suffixed name may be taken by title of other article, e.g. "答疑-15"
+			name = base + filenamify.Replacement + strconv.Itoa(a.AID)
+			if n > 1 {
+				name += filenamify.Replacement + strconv.Itoa(n)
+			}
+		}
+		used[strings.ToLower(name)] = true
+		a.Name = name
+		articles[i] = a
+	}
+	course.Articles = articles
+	return course
+}
+
+// name render path of article relative to course dir without extension
+func (t Template) name(a geektime.Article, index, chapterIndex int) string {
+	var segments []string
+	for i, segment := range t.segments {
+		isFile := i == len(t.segments)-1
+		if !isFile && a.SectionTitle == "" && hasField(segment, fieldChapter, fieldChapterIndex) {
+			continue
+		}
+		var sb strings.Builder
+		for _, tok := range segment {
+			switch tok.field {
+			case "":
+				sb.WriteString(tok.text)
+			case fieldChapter:
+				sb.WriteString(a.SectionTitle)
+			case fieldTitle:
+				sb.WriteString(a.Title)
+			case fieldIndex:
+				sb.WriteString(pad(index, tok.width))
+			case fieldChapterIndex:
+				sb.WriteString(pad(chapterIndex, tok.width))
+			case fieldID:
+				sb.WriteString(pad(a.AID, tok.width))
+			}
+		}
+		name := filenamify.Filenamify(sb.String())
+		if name == "" {
+			if !isFile {
+				continue
+			}
+			name = strconv.Itoa(a.AID)
+		}
+		segments = append(segments, name)
+	}
+	return filepath.Join(segments...)
+}
+
+func pad(n, width int) string {
+	return fmt.Sprintf("%0*d", width, n)
+}
@@ -0,0 +1,105 @@
+package naming
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/nicoxiang/geektime-downloader/internal/geektime"
+)
+
+func TestParse(t *testing.T) {
+	for _, s := range []string{
+		"",
+		DefaultTemplate,
+		"{course}/{chapter}/{title}.{ext}",
+		"{course}/{id}.{ext}",
+	} {
+		if _, err := Parse(s); err != nil {
+			t.Errorf("Parse(%q) error: %v", s, err)
+		}
+	}
+	for _, s := range []string{
+		"{title}.{ext}",
+		"{course}/{title}",
+		"{course}/{chapter}.{ext}",
+		"{course}/{index:3}-{title}.{ext}",
+		"{course}/{title:02}.{ext}",
+		"{course}/{author}/{title}.{ext}",
+		"{course}/../{title}.{ext}",
+		"{course}//{title}.{ext}",
+		"{course}/{title}.{ext}.{ext}",
+	} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) want error", s)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	course := geektime.Course{Articles: []geektime.Article{
+		{AID: 11, Title: "开篇词 | 为什么学"},
+		{AID: 12, SectionTitle: "基础篇", Title: "01 | 入门"},
+		{AID: 13, SectionTitle: "基础篇", Title: "答疑"},
+		{AID: 14, SectionTitle: "进阶篇", Title: "答疑"},
+		{AID: 15, SectionTitle: "进阶篇", Title: "答疑"},
+	}}
+
+	tmpl, err := Parse("{course}/{title}.{ext}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"开篇词-为什么学", "01-入门", "答疑", "答疑-14", "答疑-15"}
+	if got := names(tmpl.Apply(course)); !reflect.DeepEqual(got, want) {
+		t.Errorf("names = %q, want %q", got, want)
+	}
+	if course.Articles[0].Name != "" {
+		t.Error("Apply modified articles of course")
+	}
+
+	// suffixed name is taken by title of article before
+	taken := geektime.Course{Articles: []geektime.Article{
+		{AID: 21, Title: "答疑-23"},
+		{AID: 22, Title: "答疑"},
+		{AID: 23, Title: "答疑"},
+		{AID: 24, Title: "答疑"},
+	}}
+	want = []string{"答疑-23", "答疑", "答疑-23-2", "答疑-24"}
+	if got := names(tmpl.Apply(taken)); !reflect.DeepEqual(got, want) {
+		t.Errorf("names = %q, want %q", got, want)
+	}
+
+	tmpl, err = Parse(DefaultTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{
+		"001-开篇词-为什么学",
+		filepath.Join("01-基础篇", "002-01-入门"),
+		filepath.Join("01-基础篇", "003-答疑"),
+		filepath.Join("02-进阶篇", "004-答疑"),
+		filepath.Join("02-进阶篇", "005-答疑"),
+	}
+	if got := names(tmpl.Apply(course)); !reflect.DeepEqual(got, want) {
+		t.Errorf("names = %q, want %q", got, want)
+	}
+
+	// courses without chapter stay flat
+	flat := geektime.Course{Articles: make([]geektime.Article, len(course.Articles))}
+	for i, a := range course.Articles {
+		a.SectionTitle = ""
+		flat.Articles[i] = a
+	}
+	want = []string{"001-开篇词-为什么学", "002-01-入门", "003-答疑", "004-答疑", "005-答疑"}
+	if got := names(tmpl.Apply(flat)); !reflect.DeepEqual(got, want) {
+		t.Errorf("names = %q, want %q", got, want)
+	}
+}
+
+func names(course geektime.Course) []string {
+	var result []string
+	for _, a := range course.Articles {
+		result = append(result, a.Name)
+	}
+	return result
+}
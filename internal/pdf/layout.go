@@ -0,0 +1,171 @@
+package pdf
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp/device"
+)
+
+const (
+	// ThemeLight ...
+	ThemeLight = "light"
+	// ThemeDark ...
+	ThemeDark = "dark"
+
+	// DeviceDesktop means no device emulation, page is rendered in a desktop viewport
+	DeviceDesktop = "desktop"
+	// DefaultDevice ...
+	DefaultDevice = "ipad-pro-11"
+
+	mmPerInch = 25.4
+	// default margin used before page layout is configurable, 0.4 inch
+	defaultMargin = 0.4 * mmPerInch
+)
+
+// paper sizes in millimeter, portrait
+var paperSizes = map[string][2]float64{
+	"a3":     {297, 420},
+	"a4":     {210, 297},
+	"a5":     {148, 210},
+	"b5":     {176, 250},
+	"letter": {215.9, 279.4},
+	"legal":  {215.9, 355.6},
+}
+
+// emulated devices for chrome engine, e-reader sized devices produce e-reader sized pdf
+var devices = map[string]device.Info{
+	"ipad-pro-11":     device.IPadPro11.Device(),
+	"ipad-pro":        device.IPadPro.Device(),
+	"ipad":            device.IPad.Device(),
+	"ipad-mini":       device.IPadMini.Device(),
+	"kindle-fire-hdx": device.KindleFireHDX.Device(),
+	"galaxy-tab-s4":   device.GalaxyTabS4.Device(),
+	"nexus-7":         device.Nexus7.Device(),
+}
+
+// PageLayout describe paper, margins, header/footer and theme of generated pdf
+type PageLayout struct {
+	// PaperWidth and PaperHeight in millimeter, zero means engine default paper size,
+	// Letter for chrome and A4 for native
+	PaperWidth  float64
+	PaperHeight float64
+	// Margins in millimeter, top, right, bottom, left
+	Margins   [4]float64
+	Landscape bool
+	Scale     float64
+	// HeaderTemplate and FooterTemplate support placeholders {title}, {page}, {pages} and {date}
+	HeaderTemplate  string
+	FooterTemplate  string
+	PrintBackground bool
+	Theme           string
+	Device          string
+}
+
+// DefaultPageLayout ...
+func DefaultPageLayout() PageLayout {
+	return PageLayout{
+		Margins: [4]float64{defaultMargin, defaultMargin, defaultMargin, defaultMargin},
+		Scale:   1,
+		Theme:   ThemeLight,
+		Device:  DefaultDevice,
+	}
+}
+
+// ParsePaperSize parse paper name like A4, Letter or custom size like 150x200 in millimeter
+func ParsePaperSize(s string) (width, height float64, err error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return 0, 0, nil
+	}
+	if size, ok := paperSizes[s]; ok {
+		return size[0], size[1], nil
+	}
+	parts := strings.Split(s, "x")
+	if len(parts) == 2 {
+		width, err1 := strconv.ParseFloat(parts[0], 64)
+		height, err2 := strconv.ParseFloat(parts[1], 64)
+		if err1 == nil && err2 == nil && width > 0 && height > 0 {
+			return width, height, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("不支持的纸张大小: %s", s)
+}
+
+// ParseMargins parse margins in millimeter like css shorthand, "10", "10,15" or "10,15,10,15"
+func ParseMargins(s string) ([4]float64, error) {
+	var margins [4]float64
+	var values []float64
+	for _, p := range strings.Split(s, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil || v < 0 {
+			return margins, fmt.Errorf("页边距格式不合法: %s", s)
+		}
+		values = append(values, v)
+	}
+	switch len(values) {
+	case 1:
+		margins = [4]float64{values[0], values[0], values[0], values[0]}
+	case 2:
+		margins = [4]float64{values[0], values[1], values[0], values[1]}
+	case 4:
+		copy(margins[:], values)
+	default:
+		return margins, fmt.Errorf("页边距格式不合法: %s", s)
+	}
+	return margins, nil
+}
+
+// Validate ...
+func (l PageLayout) Validate() error {
+	if l.Theme != ThemeLight && l.Theme != ThemeDark {
+		return fmt.Errorf("不支持的主题: %s", l.Theme)
+	}
+	if _, ok := devices[l.Device]; !ok && l.Device != DeviceDesktop {
+		return fmt.Errorf("不支持的模拟设备: %s", l.Device)
+	}
+	// same range as chrome printToPDF
+	if l.Scale < 0.1 || l.Scale > 2 {
+		return fmt.Errorf("缩放比例需在 0.1 到 2 之间: %v", l.Scale)
+	}
+	return nil
+}
+
+// paperSize return portrait paper size in millimeter
+func (l PageLayout) paperSize(defaultWidth, defaultHeight float64) (float64, float64) {
+	if l.PaperWidth == 0 || l.PaperHeight == 0 {
+		return defaultWidth, defaultHeight
+	}
+	return l.PaperWidth, l.PaperHeight
+}
+
+func (l PageLayout) hasHeaderFooter() bool {
+	return l.HeaderTemplate != "" || l.FooterTemplate != ""
+}
+
+// expandTemplate replace placeholders in header/footer template
+func expandTemplate(tpl, title, page, pages string) string {
+	return strings.NewReplacer(
+		"{title}", title,
+		"{page}", page,
+		"{pages}", pages,
+		"{date}", time.Now().Format("2006-01-02"),
+	).Replace(tpl)
+}
+
+// chromeTemplate convert header/footer template to chrome printToPDF html template
+func chromeTemplate(tpl, title string) string {
+	if tpl == "" {
+		// empty template makes chrome print its default header/footer
+		return "<span></span>"
+	}
+	content := expandTemplate(html.EscapeString(tpl),
+		html.EscapeString(title),
+		`<span class="pageNumber"></span>`,
+		`<span class="totalPages"></span>`,
+	)
+	return `<div style="width:100%;font-size:8px;color:#888;text-align:center;">` + content + `</div>`
+}
@@ -0,0 +1,308 @@
+package pdf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/nicoxiang/geektime-downloader/internal/geektime"
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/filenamify"
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/files"
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/logger"
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+const (
+	// points per millimeter
+	mmToPt        = 72 / 25.4
+	tocLineHeight = 7.0
+)
+
+// ErrNoPDFToMerge ...
+var ErrNoPDFToMerge = errors.New("没有可合并的 PDF 文件")
+
+func init() {
+	// do not create pdfcpu config folder in user config dir
+	api.DisableConfigDir()
+}
+
+type mergeEntry struct {
+	article   geektime.Article
+	fileName  string
+	pageCount int
+	// first page number of this article in the merged pdf
+	page int
+}
+
+type tocLink struct {
+	// page number in front matter
+	frontPage  int
+	x, y, w, h float64
+	target     int
+}
+
+// MergeCoursePDF concatenates article pdfs in dir into <course>.pdf next to dir
+// in course articles order, with a cover page, a clickable table of contents,
+// outline bookmarks grouped by section and document metadata. Cover and table of
+// contents need CJK font, they are left out if no font is found.
+func MergeCoursePDF(ctx context.Context, course geektime.Course, dir, fontPath string, layout PageLayout) (string, error) {
+	var entries []*mergeEntry
+	for _, a := range course.Articles {
+		fileName := filepath.Join(dir, a.FileName()+PDFExtension)
+		if !files.CheckFileExists(fileName) {
+			logger.Warnf("Merge course pdf skip article %s, file not exists", a.Title)
+			continue
+		}
+		pageCount, err := api.PageCountFile(fileName)
+		if err != nil {
+			return "", fmt.Errorf("读取 PDF %s 失败: %w", fileName, err)
+		}
+		entries = append(entries, &mergeEntry{article: a, fileName: fileName, pageCount: pageCount})
+	}
+	if len(entries) == 0 {
+		return "", ErrNoPDFToMerge
+	}
+
+	// articles printed by chrome engine need no font, only front matter is left out without it
+	font, err := loadFont(fontPath)
+	if errors.Is(err, ErrCJKFontNotFound) {
+		logger.Warnf("Merge course pdf without cover and toc: %v", err)
+	} else if err != nil {
+		return "", err
+	}
+
+	var inFiles []string
+	var links []tocLink
+	var pageHeight float64
+	if font == nil {
+		assignPageNumbers(entries, 0)
+	} else {
+		var frontFile string
+		frontFile, links, pageHeight, err = writeFrontMatter(course, entries, dir, font, layout)
+		if err != nil {
+			return "", err
+		}
+		defer func() {
+			_ = os.Remove(frontFile)
+		}()
+		inFiles = append(inFiles, frontFile)
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	for _, e := range entries {
+		inFiles = append(inFiles, e.fileName)
+	}
+
+	out := filepath.Join(filepath.Dir(dir), filenamify.Filenamify(course.Title)+PDFExtension)
+	if err := writeMergedPDF(out, inFiles, course, entries, links, pageHeight); err != nil {
+		_ = os.Remove(out)
+		return "", err
+	}
+	return out, nil
+}
+
+// writeFrontMatter render cover and toc into a temp file in dir and assign page numbers of entries
+// after them, links and page height of toc are returned for link annotations
+func writeFrontMatter(course geektime.Course, entries []*mergeEntry, dir string, font []byte, layout PageLayout) (string, []tocLink, float64, error) {
+	// the number of toc pages is unknown before rendering, render again if guess is wrong
+	frontPages := 2
+	var front *fpdf.Fpdf
+	var links []tocLink
+	for {
+		assignPageNumbers(entries, frontPages)
+		front, links = renderFrontMatter(course, entries, font, layout)
+		if front.Err() {
+			return "", nil, 0, front.Error()
+		}
+		if front.PageNo() == frontPages {
+			break
+		}
+		frontPages = front.PageNo()
+	}
+
+	f, err := os.CreateTemp(dir, "front-*"+PDFExtension)
+	if err != nil {
+		return "", nil, 0, err
+	}
+	_ = f.Close()
+	if err := front.OutputFileAndClose(f.Name()); err != nil {
+		_ = os.Remove(f.Name())
+		return "", nil, 0, err
+	}
+	_, pageHeight := front.GetPageSize()
+	return f.Name(), links, pageHeight, nil
+}
+
+func writeMergedPDF(out string, inFiles []string, course geektime.Course, entries []*mergeEntry, links []tocLink, pageHeight float64) error {
+	conf := model.NewDefaultConfiguration()
+	conf.ValidationMode = model.ValidationRelaxed
+	conf.CreateBookmarks = false
+
+	if err := api.MergeCreateFile(inFiles, out, false, conf); err != nil {
+		return err
+	}
+
+	annotations := make(map[int][]model.AnnotationRenderer)
+	for _, l := range links {
+		rect := types.NewRectangle(
+			l.x*mmToPt,
+			(pageHeight-l.y-l.h)*mmToPt,
+			(l.x+l.w)*mmToPt,
+			(pageHeight-l.y)*mmToPt,
+		)
+		dest := &model.Destination{Typ: model.DestFit, PageNr: l.target}
+		ann := model.NewLinkAnnotation(*rect, "", "", "", 0, nil, dest, "", nil, false, 0, model.BSSolid)
+		annotations[l.frontPage] = append(annotations[l.frontPage], ann)
+	}
+	if len(annotations) > 0 {
+		if err := api.AddAnnotationsMapFile(out, "", annotations, conf, false); err != nil {
+			return err
+		}
+	}
+
+	if err := api.AddBookmarksFile(out, "", buildBookmarks(entries), true, conf); err != nil {
+		return err
+	}
+
+	properties := map[string]string{
+		"Title":    course.Title,
+		"Subject":  course.Title,
+		"Creator":  "geektime-downloader",
+		"CourseID": strconv.Itoa(course.ID),
+	}
+	if course.Author != "" {
+		properties["Author"] = course.Author
+	}
+	return api.AddPropertiesFile(out, "", properties, conf)
+}
+
+func assignPageNumbers(entries []*mergeEntry, frontPages int) {
+	page := frontPages + 1
+	for _, e := range entries {
+		e.page = page
+		page += e.pageCount
+	}
+}
+
+// buildBookmarks group article bookmarks by section title, toc is bookmarked if there is front matter
+func buildBookmarks(entries []*mergeEntry) []pdfcpu.Bookmark {
+	var bms []pdfcpu.Bookmark
+	if entries[0].page > 1 {
+		bms = append(bms, pdfcpu.Bookmark{Title: "目录", PageFrom: 2})
+	}
+	for _, e := range entries {
+		bm := pdfcpu.Bookmark{Title: e.article.Title, PageFrom: e.page}
+		section := e.article.SectionTitle
+		if section == "" {
+			bms = append(bms, bm)
+			continue
+		}
+		if n := len(bms); n == 0 || len(bms[n-1].Kids) == 0 || bms[n-1].Title != section {
+			bms = append(bms, pdfcpu.Bookmark{Title: section, PageFrom: e.page, Bold: true})
+		}
+		last := &bms[len(bms)-1]
+		last.Kids = append(last.Kids, bm)
+	}
+	return bms
+}
+
+func renderFrontMatter(course geektime.Course, entries []*mergeEntry, font []byte, layout PageLayout) (*fpdf.Fpdf, []tocLink) {
+	// front matter use the same paper as articles, A4 if not specified
+	paperWidth, paperHeight := layout.paperSize(210, 297)
+	orientation := "P"
+	if layout.Landscape {
+		orientation = "L"
+	}
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		OrientationStr: orientation,
+		UnitStr:        "mm",
+		Size:           fpdf.SizeType{Wd: paperWidth, Ht: paperHeight},
+	})
+	pdf.AddUTF8FontFromBytes(nativeFontFamily, "", font)
+	pdf.AddUTF8FontFromBytes(nativeFontFamily, "B", font)
+	pdf.SetMargins(20, 20, 20)
+	pdf.SetAutoPageBreak(true, 20)
+
+	// cover
+	pdf.AddPage()
+	_, coverHeight := pdf.GetPageSize()
+	pdf.SetY(coverHeight * 0.3)
+	pdf.SetFont(nativeFontFamily, "B", 26)
+	pdf.MultiCell(0, 13, course.Title, "", "C", false)
+	pdf.Ln(10)
+	pdf.SetFont(nativeFontFamily, "", 14)
+	if course.Author != "" {
+		pdf.MultiCell(0, 8, course.Author, "", "C", false)
+	}
+	pdf.SetTextColor(106, 115, 125)
+	pdf.SetFont(nativeFontFamily, "", 11)
+	pdf.MultiCell(0, 7, fmt.Sprintf("课程 ID: %d", course.ID), "", "C", false)
+	pdf.MultiCell(0, 7, fmt.Sprintf("共 %d 篇文章", len(entries)), "", "C", false)
+	pdf.MultiCell(0, 7, "生成于 "+time.Now().Format("2006-01-02"), "", "C", false)
+	pdf.SetTextColor(0, 0, 0)
+
+	// table of contents
+	pdf.AddPage()
+	pdf.SetFont(nativeFontFamily, "B", 18)
+	pdf.CellFormat(0, 12, "目录", "", 1, "L", false, 0, "")
+	pdf.Ln(3)
+
+	left, _, right, _ := pdf.GetMargins()
+	pageWidth, _ := pdf.GetPageSize()
+	width := pageWidth - left - right
+	numberWidth := 15.0
+
+	var links []tocLink
+	section := ""
+	for _, e := range entries {
+		indent := 0.0
+		if e.article.SectionTitle != "" {
+			indent = 5
+			if e.article.SectionTitle != section {
+				section = e.article.SectionTitle
+				pdf.SetFont(nativeFontFamily, "B", 12)
+				pdf.Ln(2)
+				pdf.CellFormat(0, tocLineHeight, section, "", 1, "L", false, 0, "")
+			}
+		}
+
+		pdf.SetFont(nativeFontFamily, "", 11)
+		title := e.article.Title
+		titleWidth := width - indent - numberWidth
+		if lines := pdf.SplitText(title, titleWidth); len(lines) > 1 {
+			// first line may be empty if even one rune does not fit
+			if first := []rune(lines[0]); len(first) > 0 {
+				title = string(first[:len(first)-1]) + "…"
+			}
+		}
+
+		// page break before measuring position, so that link rect is on the right page
+		_, pageHeight := pdf.GetPageSize()
+		_, _, _, bottom := pdf.GetMargins()
+		if pdf.GetY()+tocLineHeight > pageHeight-bottom {
+			pdf.AddPage()
+		}
+		y := pdf.GetY()
+		pdf.SetX(left + indent)
+		pdf.CellFormat(titleWidth, tocLineHeight, title, "", 0, "L", false, 0, "")
+		pdf.CellFormat(numberWidth, tocLineHeight, strconv.Itoa(e.page), "", 1, "R", false, 0, "")
+		links = append(links, tocLink{
+			frontPage: pdf.PageNo(),
+			x:         left + indent,
+			y:         y,
+			w:         width - indent,
+			h:         tocLineHeight,
+			target:    e.page,
+		})
+	}
+	return pdf, links
+}
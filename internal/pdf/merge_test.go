@@ -0,0 +1,264 @@
+package pdf
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/nicoxiang/geektime-downloader/internal/geektime"
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestMergeCoursePDF(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "course")
+	course := geektime.Course{ID: 100, Title: "course", Author: "author", Articles: []geektime.Article{
+		{AID: 1, Title: "preface"},
+		{AID: 2, Title: "lesson 1", SectionTitle: "basics"},
+		{AID: 3, Title: "missing", SectionTitle: "basics"},
+		{AID: 4, Title: "lesson 2", SectionTitle: "basics"},
+	}}
+	writeArticlePDF(t, dir, course.Articles[0], 1)
+	writeArticlePDF(t, dir, course.Articles[1], 3)
+	writeArticlePDF(t, dir, course.Articles[3], 2)
+
+	out, err := MergeCoursePDF(context.Background(), course, dir, writeFont(t), DefaultPageLayout())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(filepath.Dir(dir), "course.pdf"); out != want {
+		t.Errorf("out = %s, want %s", out, want)
+	}
+	// cover, toc, then articles of 1, 3 and 2 pages
+	if n, err := api.PageCountFile(out); err != nil || n != 8 {
+		t.Errorf("page count = %d, %v, want 8", n, err)
+	}
+
+	want := []string{"目录 2", "preface 3", "basics 4", "  lesson 1 4", "  lesson 2 7"}
+	if got := readBookmarks(t, out); !reflect.DeepEqual(got, want) {
+		t.Errorf("bookmarks = %q, want %q", got, want)
+	}
+	links := readLinks(t, out)
+	if got, want := linkTargets(links[2]), []int{3, 4, 7}; len(links) != 1 || !reflect.DeepEqual(got, want) {
+		t.Fatalf("toc links = %v, want %v on page 2", links, want)
+	}
+	// A4 in points, toc lines are below each other and indented in section
+	for i, l := range links[2] {
+		if l.rect.LL.X < 0 || l.rect.UR.X > 595.3 || l.rect.LL.Y < 0 || l.rect.UR.Y > 841.9 {
+			t.Errorf("link %d rect %v is out of page", i, l.rect)
+		}
+		if i > 0 && l.rect.UR.Y > links[2][i-1].rect.LL.Y+0.01 {
+			t.Errorf("link %d rect %v is not below %v", i, l.rect, links[2][i-1].rect)
+		}
+	}
+	if links[2][1].rect.LL.X <= links[2][0].rect.LL.X {
+		t.Error("link of article in section is not indented")
+	}
+}
+
+func TestMergeCoursePDFWithoutFont(t *testing.T) {
+	if FindCJKFont() != "" {
+		t.Skip("system CJK font is used when font path is empty")
+	}
+	dir := filepath.Join(t.TempDir(), "course")
+	course := geektime.Course{Title: "course", Articles: []geektime.Article{
+		{AID: 1, Title: "lesson 1", SectionTitle: "basics"},
+		{AID: 2, Title: "lesson 2", SectionTitle: "basics"},
+	}}
+	writeArticlePDF(t, dir, course.Articles[0], 2)
+	writeArticlePDF(t, dir, course.Articles[1], 1)
+
+	// chrome printed pdfs are merged without cover and toc
+	out, err := MergeCoursePDF(context.Background(), course, dir, "", DefaultPageLayout())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := api.PageCountFile(out); err != nil || n != 3 {
+		t.Errorf("page count = %d, %v, want 3", n, err)
+	}
+	want := []string{"basics 1", "  lesson 1 1", "  lesson 2 3"}
+	if got := readBookmarks(t, out); !reflect.DeepEqual(got, want) {
+		t.Errorf("bookmarks = %q, want %q", got, want)
+	}
+}
+
+func TestMergeCoursePDFLongTOC(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "course")
+	course := geektime.Course{Title: "course"}
+	for i := 1; i <= 60; i++ {
+		a := geektime.Article{AID: i, Title: "lesson " + strconv.Itoa(i), SectionTitle: "part " + strconv.Itoa((i-1)/20+1)}
+		course.Articles = append(course.Articles, a)
+		writeArticlePDF(t, dir, a, 1)
+	}
+
+	out, err := MergeCoursePDF(context.Background(), course, dir, writeFont(t), DefaultPageLayout())
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := api.PageCountFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// toc does not fit in one page, articles are shifted by the extra toc pages
+	frontPages := n - len(course.Articles)
+	if frontPages <= 2 {
+		t.Fatalf("front pages = %d, want toc of more than one page", frontPages)
+	}
+
+	var targets []int
+	links := readLinks(t, out)
+	for page := 2; page <= frontPages; page++ {
+		if len(links[page]) == 0 {
+			t.Errorf("toc page %d has no links", page)
+		}
+		targets = append(targets, linkTargets(links[page])...)
+	}
+	for i, target := range targets {
+		if want := frontPages + 1 + i; target != want {
+			t.Fatalf("link %d target = %d, want %d", i, target, want)
+		}
+	}
+	if len(targets) != len(course.Articles) {
+		t.Errorf("%d toc links, want %d", len(targets), len(course.Articles))
+	}
+
+	bms := readBookmarks(t, out)
+	if want := "part 1 " + strconv.Itoa(frontPages+1); bms[1] != want {
+		t.Errorf("first section bookmark = %q, want %q", bms[1], want)
+	}
+	if len(bms) != 1+3+60 {
+		t.Errorf("%d bookmarks, want toc, 3 sections and 60 articles", len(bms))
+	}
+}
+
+func TestRenderFrontMatterLongTitle(t *testing.T) {
+	// title breaking after a leading separator has empty first line, truncating it must not panic
+	entries := []*mergeEntry{
+		{article: geektime.Article{Title: " " + strings.Repeat("x", 200), SectionTitle: "basics"}, pageCount: 1},
+	}
+	assignPageNumbers(entries, 2)
+	front, links := renderFrontMatter(geektime.Course{Title: "course"}, entries, goregular.TTF, DefaultPageLayout())
+	if front.Err() {
+		t.Fatal(front.Error())
+	}
+	if len(links) != 1 || links[0].target != 3 || links[0].frontPage != 2 {
+		t.Errorf("links = %+v", links)
+	}
+}
+
+func writeFont(t *testing.T) string {
+	t.Helper()
+	fontPath := filepath.Join(t.TempDir(), "font.ttf")
+	if err := os.WriteFile(fontPath, goregular.TTF, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return fontPath
+}
+
+func writeArticlePDF(t *testing.T, dir string, article geektime.Article, pages int) {
+	t.Helper()
+	doc := fpdf.New("P", "mm", "A4", "")
+	doc.SetFont("Helvetica", "", 12)
+	for i := 0; i < pages; i++ {
+		doc.AddPage()
+		doc.Cell(0, 10, article.Title)
+	}
+	fileName := filepath.Join(dir, article.FileName()+PDFExtension)
+	if err := os.MkdirAll(filepath.Dir(fileName), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.OutputFileAndClose(fileName); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readBookmarks flatten bookmark tree as "title page", kids are indented
+func readBookmarks(t *testing.T, fileName string) []string {
+	t.Helper()
+	f, err := os.Open(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	bms, err := api.Bookmarks(f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result []string
+	var walk func(bms []pdfcpu.Bookmark, indent string)
+	walk = func(bms []pdfcpu.Bookmark, indent string) {
+		for _, bm := range bms {
+			result = append(result, indent+bm.Title+" "+strconv.Itoa(bm.PageFrom))
+			walk(bm.Kids, indent+"  ")
+		}
+	}
+	walk(bms, "")
+	return result
+}
+
+type pageLink struct {
+	rect   *types.Rectangle
+	target int
+}
+
+func linkTargets(links []pageLink) []int {
+	var targets []int
+	for _, l := range links {
+		targets = append(targets, l.target)
+	}
+	return targets
+}
+
+// readLinks return link annotations by page, in toc order
+func readLinks(t *testing.T, fileName string) map[int][]pageLink {
+	t.Helper()
+	ctx, err := api.ReadContextFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	links := make(map[int][]pageLink)
+	for page := 1; page <= ctx.PageCount; page++ {
+		d, _, _, err := ctx.PageDict(page, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		annots, err := ctx.DereferenceArray(d["Annots"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, o := range annots {
+			annot, err := ctx.DereferenceDict(o)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if annot.NameEntry("Subtype") == nil || *annot.NameEntry("Subtype") != "Link" {
+				continue
+			}
+			dest, err := ctx.DereferenceArray(annot["Dest"])
+			if err != nil || len(dest) == 0 {
+				t.Fatalf("link on page %d has no destination: %v", page, err)
+			}
+			ref, ok := dest[0].(types.IndirectRef)
+			if !ok {
+				t.Fatalf("destination of link on page %d is not a page: %v", page, dest)
+			}
+			target, err := ctx.PageNumber(ref.ObjectNumber.Value())
+			if err != nil {
+				t.Fatal(err)
+			}
+			rect, err := ctx.RectForArray(annot.ArrayEntry("Rect"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			links[page] = append(links[page], pageLink{rect: rect, target: target})
+		}
+	}
+	return links
+}
@@ -0,0 +1,734 @@
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/nicoxiang/geektime-downloader/internal/geektime"
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/files"
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/logger"
+	"golang.org/x/image/webp"
+	"golang.org/x/net/html"
+)
+
+const (
+	// EngineChrome print article page to pdf by headless Chrome
+	EngineChrome = "chrome"
+	// EngineNative render article html to pdf in pure go, Chrome is not needed
+	EngineNative = "native"
+)
+
+const (
+	nativeFontFamily = "cjk"
+	nativeFontSize   = 11.0
+	nativeCodeSize   = 9.0
+	nativeLineHeight = 6.0
+	nativeCodeHeight = 4.6
+	// stroke width in mm per font size in pt to simulate bold
+	nativeBoldStroke = 0.01
+	// skew angle in degree to simulate italic
+	nativeItalicSkew = 12.0
+)
+
+// ErrCJKFontNotFound ...
+var ErrCJKFontNotFound = errors.New("未找到可用的中文 TrueType 字体, 请通过 --pdf-font 指定字体文件路径(.ttf)")
+
+// well known CJK truetype fonts, fpdf does not support ttc and otf(cff) fonts
+var cjkFontPaths = []string{
+	`C:\Windows\Fonts\simhei.ttf`,
+	`C:\Windows\Fonts\simkai.ttf`,
+	`C:\Windows\Fonts\simfang.ttf`,
+	"/System/Library/Fonts/Supplemental/Arial Unicode.ttf",
+	"/Library/Fonts/Arial Unicode.ttf",
+	"/usr/share/fonts/truetype/droid/DroidSansFallbackFull.ttf",
+	"/usr/share/fonts/google-droid/DroidSansFallbackFull.ttf",
+	"/usr/share/fonts/droid/DroidSansFallbackFull.ttf",
+	"/usr/share/fonts/truetype/arphic-gkai00mp/gkai00mp.ttf",
+	"/usr/share/fonts/truetype/wqy/wqy-microhei.ttf",
+}
+
+var whitespaceRegexp = regexp.MustCompile(`[ \t\r\n]+`)
+
+var headingFontSizes = map[string]float64{
+	"h1": 20,
+	"h2": 17,
+	"h3": 15,
+	"h4": 13,
+	"h5": 12,
+	"h6": 11,
+}
+
+// FindCJKFont return the first CJK truetype font found in system font folders
+func FindCJKFont() string {
+	for _, p := range cjkFontPaths {
+		if files.CheckFileExists(p) {
+			return p
+		}
+	}
+	return ""
+}
+
+// loadFont read font file, fallback to system CJK font if fontPath is empty
+func loadFont(fontPath string) ([]byte, error) {
+	if fontPath == "" {
+		fontPath = FindCJKFont()
+	}
+	if fontPath == "" {
+		return nil, ErrCJKFontNotFound
+	}
+	return os.ReadFile(fontPath)
+}
+
+// RenderArticleToPDF render article html content to pdf without Chrome, pdf is saved as name in
+// dir, name may contain sub dirs
+func RenderArticleToPDF(ctx context.Context,
+	articleHTML,
+	dir,
+	name,
+	title,
+	fontPath string,
+	layout PageLayout,
+	overwrite bool,
+) (bool, error) {
+	fileName := filepath.Join(dir, name+PDFExtension)
+
+	if files.CheckFileExists(fileName) && !overwrite {
+		return true, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(fileName), os.ModePerm); err != nil {
+		return false, err
+	}
+
+	font, err := loadFont(fontPath)
+	if err != nil {
+		return false, err
+	}
+
+	doc, err := html.Parse(strings.NewReader(articleHTML))
+	if err != nil {
+		return false, err
+	}
+
+	r := newNativeRenderer(ctx, font, layout, title)
+	r.pdf.SetTitle(title, true)
+	r.heading("h1", title)
+	r.renderChildren(doc)
+
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	if err := r.pdf.OutputFileAndClose(fileName); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+type nativeRenderer struct {
+	ctx      context.Context
+	pdf      *fpdf.Fpdf
+	palette  nativePalette
+	scale    float64
+	fontSize float64
+	bold     bool
+	italic   bool
+	link     string
+	lists    []*nativeList
+	images   int
+}
+
+type nativeList struct {
+	ordered bool
+	index   int
+}
+
+type rgb [3]int
+
+type nativePalette struct {
+	background     rgb
+	text           rgb
+	muted          rgb
+	link           rgb
+	code           rgb
+	codeBackground rgb
+	border         rgb
+}
+
+var (
+	lightPalette = nativePalette{
+		background:     rgb{255, 255, 255},
+		text:           rgb{0, 0, 0},
+		muted:          rgb{106, 115, 125},
+		link:           rgb{3, 102, 214},
+		code:           rgb{199, 37, 78},
+		codeBackground: rgb{246, 248, 250},
+		border:         rgb{200, 200, 200},
+	}
+	darkPalette = nativePalette{
+		background:     rgb{30, 30, 30},
+		text:           rgb{220, 220, 220},
+		muted:          rgb{150, 150, 150},
+		link:           rgb{88, 166, 255},
+		code:           rgb{255, 123, 114},
+		codeBackground: rgb{45, 45, 45},
+		border:         rgb{90, 90, 90},
+	}
+)
+
+func newNativeRenderer(ctx context.Context, font []byte, layout PageLayout, title string) *nativeRenderer {
+	orientation := "P"
+	if layout.Landscape {
+		orientation = "L"
+	}
+	// portrait size, fpdf swaps width and height for landscape itself
+	width, height := layout.paperSize(210, 297)
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		OrientationStr: orientation,
+		UnitStr:        "mm",
+		Size:           fpdf.SizeType{Wd: width, Ht: height},
+	})
+	// CJK fonts have no bold or italic variants, both styles are simulated when writing text
+	pdf.AddUTF8FontFromBytes(nativeFontFamily, "", font)
+	top, right, bottom, left := layout.Margins[0], layout.Margins[1], layout.Margins[2], layout.Margins[3]
+	pdf.SetMargins(left, top, right)
+	pdf.SetAutoPageBreak(true, bottom)
+
+	r := &nativeRenderer{ctx: ctx, pdf: pdf, palette: lightPalette, scale: layout.Scale}
+	if layout.Theme == ThemeDark {
+		r.palette = darkPalette
+	}
+	if r.scale <= 0 {
+		r.scale = 1
+	}
+	r.fontSize = nativeFontSize * r.scale
+
+	pdf.AliasNbPages("")
+	pdf.SetHeaderFuncMode(func() {
+		pageWidth, pageHeight := pdf.GetPageSize()
+		if layout.Theme == ThemeDark || layout.PrintBackground {
+			r.setFillColor(r.palette.background)
+			pdf.Rect(0, 0, pageWidth, pageHeight, "F")
+		}
+		if layout.HeaderTemplate != "" {
+			r.headerFooterText(layout.HeaderTemplate, title, top/2)
+		}
+		// text rendering mode is reset on new page
+		r.applyFont()
+	}, true)
+	pdf.SetFooterFunc(func() {
+		if layout.FooterTemplate != "" {
+			_, pageHeight := pdf.GetPageSize()
+			r.headerFooterText(layout.FooterTemplate, title, pageHeight-bottom/2)
+		}
+	})
+
+	pdf.AddPage()
+	r.applyFont()
+	r.setTextColor(r.palette.text)
+	return r
+}
+
+// headerFooterText write header or footer text centered vertically at y
+func (r *nativeRenderer) headerFooterText(tpl, title string, y float64) {
+	text := expandTemplate(tpl, title, strconv.Itoa(r.pdf.PageNo()), "{nb}")
+	r.pdf.SetFont(nativeFontFamily, "", 8)
+	r.pdf.SetTextRenderingMode(0)
+	r.withTextColor(r.palette.muted, func() {
+		left, _, _, _ := r.pdf.GetMargins()
+		r.pdf.SetXY(left, y-2)
+		r.pdf.CellFormat(r.contentWidth(), 4, text, "", 0, "C", false, 0, "")
+	})
+	r.applyFont()
+}
+
+func (r *nativeRenderer) setTextColor(c rgb) {
+	r.pdf.SetTextColor(c[0], c[1], c[2])
+}
+
+// withTextColor render with text color c, then restore the color of enclosing element
+func (r *nativeRenderer) withTextColor(c rgb, render func()) {
+	old := rgb{}
+	old[0], old[1], old[2] = r.pdf.GetTextColor()
+	r.setTextColor(c)
+	render()
+	r.setTextColor(old)
+}
+
+func (r *nativeRenderer) setFillColor(c rgb) {
+	r.pdf.SetFillColor(c[0], c[1], c[2])
+}
+
+func (r *nativeRenderer) setDrawColor(c rgb) {
+	r.pdf.SetDrawColor(c[0], c[1], c[2])
+}
+
+func (r *nativeRenderer) applyFont() {
+	r.pdf.SetFont(nativeFontFamily, "", r.fontSize)
+	if r.bold {
+		// fill then stroke glyph outlines
+		r.pdf.SetTextRenderingMode(2)
+	} else {
+		r.pdf.SetTextRenderingMode(0)
+	}
+}
+
+// strokeBold set stroke to text color for bold text, the returned func restores previous stroke
+func (r *nativeRenderer) strokeBold() func() {
+	if !r.bold {
+		return func() {}
+	}
+	width := r.pdf.GetLineWidth()
+	dr, dg, db := r.pdf.GetDrawColor()
+	r.pdf.SetLineWidth(r.fontSize * nativeBoldStroke)
+	r.pdf.SetDrawColor(r.pdf.GetTextColor())
+	return func() {
+		r.pdf.SetLineWidth(width)
+		r.pdf.SetDrawColor(dr, dg, db)
+	}
+}
+
+func (r *nativeRenderer) lineHeight() float64 {
+	return nativeLineHeight * r.fontSize / nativeFontSize
+}
+
+func (r *nativeRenderer) contentWidth() float64 {
+	w, _ := r.pdf.GetPageSize()
+	left, _, right, _ := r.pdf.GetMargins()
+	return w - left - right
+}
+
+// lineBreak start a new line if current line is not empty
+func (r *nativeRenderer) lineBreak() {
+	left, _, _, _ := r.pdf.GetMargins()
+	if r.pdf.GetX() > left+0.1 {
+		r.pdf.Ln(r.lineHeight())
+	}
+}
+
+// blockBreak end current block and leave a small gap before next block
+func (r *nativeRenderer) blockBreak() {
+	r.lineBreak()
+	r.pdf.Ln(2)
+}
+
+func (r *nativeRenderer) renderChildren(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if r.ctx.Err() != nil || r.pdf.Err() {
+			return
+		}
+		r.render(c)
+	}
+}
+
+func (r *nativeRenderer) render(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		r.text(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		r.renderChildren(n)
+		return
+	}
+
+	switch n.Data {
+	case "script", "style", "head":
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		r.heading(n.Data, textContent(n))
+	case "p", "div", "section", "article", "figure", "figcaption":
+		r.lineBreak()
+		r.renderChildren(n)
+		r.blockBreak()
+	case "br":
+		r.pdf.Ln(r.lineHeight())
+	case "hr":
+		r.blockBreak()
+		left, _, _, _ := r.pdf.GetMargins()
+		y := r.pdf.GetY()
+		r.setDrawColor(r.palette.border)
+		r.pdf.Line(left, y, left+r.contentWidth(), y)
+		r.pdf.Ln(3)
+	case "strong", "b":
+		old := r.bold
+		r.bold = true
+		r.applyFont()
+		r.renderChildren(n)
+		r.bold = old
+		r.applyFont()
+	case "em", "i":
+		old := r.italic
+		r.italic = true
+		r.applyFont()
+		r.renderChildren(n)
+		r.italic = old
+		r.applyFont()
+	case "code":
+		r.withTextColor(r.palette.code, func() { r.renderChildren(n) })
+	case "a":
+		old := r.link
+		r.link = attr(n, "href")
+		r.withTextColor(r.palette.link, func() { r.renderChildren(n) })
+		r.link = old
+	case "pre":
+		r.pre(textContent(n))
+	case "ul", "ol":
+		r.lineBreak()
+		r.lists = append(r.lists, &nativeList{ordered: n.Data == "ol"})
+		r.indent(6)
+		r.renderChildren(n)
+		r.indent(-6)
+		r.lists = r.lists[:len(r.lists)-1]
+		if len(r.lists) == 0 {
+			r.blockBreak()
+		}
+	case "li":
+		r.listItem(n)
+	case "blockquote":
+		r.lineBreak()
+		r.indent(6)
+		r.withTextColor(r.palette.muted, func() { r.renderChildren(n) })
+		r.indent(-6)
+		r.blockBreak()
+	case "table":
+		r.table(n)
+	case "img":
+		r.image(attr(n, "src"))
+	default:
+		r.renderChildren(n)
+	}
+}
+
+func (r *nativeRenderer) text(s string) {
+	s = collapseWhitespace(s)
+	if s == "" {
+		return
+	}
+	left, _, _, _ := r.pdf.GetMargins()
+	if r.pdf.GetX() <= left+0.1 {
+		s = strings.TrimLeft(s, " ")
+	}
+	defer r.strokeBold()()
+	if r.italic {
+		r.italicText(s)
+		return
+	}
+	if r.link != "" {
+		r.pdf.WriteLinkString(r.lineHeight(), s, r.link)
+		return
+	}
+	r.pdf.Write(r.lineHeight(), s)
+}
+
+// italicText write text line by line with skewed glyphs, line is wrapped at last space if any
+func (r *nativeRenderer) italicText(s string) {
+	h := r.lineHeight()
+	pageWidth, pageHeight := r.pdf.GetPageSize()
+	left, _, right, bottom := r.pdf.GetMargins()
+	runes := []rune(s)
+	for len(runes) > 0 {
+		n := r.fitRunes(runes, pageWidth-right-r.pdf.GetX())
+		if n == 0 {
+			if r.pdf.GetX() > left+0.1 {
+				r.pdf.Ln(h)
+				continue
+			}
+			n = 1
+		}
+		if n < len(runes) {
+			if i := lastSpace(runes[:n]); i > 0 {
+				n = i + 1
+			}
+		}
+		// break page before transform, page must not be added inside transform
+		if r.pdf.GetY()+h > pageHeight-bottom {
+			r.pdf.AddPage()
+		}
+		chunk := string(runes[:n])
+		x, y := r.pdf.GetXY()
+		r.pdf.TransformBegin()
+		r.pdf.TransformSkewX(nativeItalicSkew, x, y+h/2)
+		r.pdf.CellFormat(r.pdf.GetStringWidth(chunk), h, chunk, "", 0, "L", false, 0, r.link)
+		r.pdf.TransformEnd()
+		runes = runes[n:]
+		if len(runes) > 0 {
+			r.pdf.Ln(h)
+			runes = []rune(strings.TrimLeft(string(runes), " "))
+		}
+	}
+}
+
+// fitRunes return count of leading runes fitting in width
+func (r *nativeRenderer) fitRunes(runes []rune, width float64) int {
+	w := 0.0
+	for i, c := range runes {
+		w += r.pdf.GetStringWidth(string(c))
+		if w > width {
+			return i
+		}
+	}
+	return len(runes)
+}
+
+func lastSpace(runes []rune) int {
+	for i := len(runes) - 1; i >= 0; i-- {
+		if runes[i] == ' ' {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *nativeRenderer) heading(tag, text string) {
+	r.lineBreak()
+	r.pdf.Ln(2)
+	oldSize, oldBold := r.fontSize, r.bold
+	r.fontSize, r.bold = headingFontSizes[tag]*r.scale, true
+	r.applyFont()
+	restore := r.strokeBold()
+	r.pdf.MultiCell(0, r.lineHeight(), strings.TrimSpace(collapseWhitespace(text)), "", "L", false)
+	restore()
+	r.fontSize, r.bold = oldSize, oldBold
+	r.applyFont()
+	r.pdf.Ln(2)
+}
+
+func (r *nativeRenderer) pre(code string) {
+	r.lineBreak()
+	code = strings.TrimRight(strings.ReplaceAll(code, "\t", "    "), "\n")
+	r.pdf.SetFont(nativeFontFamily, "", nativeCodeSize*r.scale)
+	r.pdf.SetTextRenderingMode(0)
+	r.setFillColor(r.palette.codeBackground)
+	r.pdf.MultiCell(0, nativeCodeHeight*r.scale, code, "", "L", true)
+	r.applyFont()
+	r.pdf.Ln(3)
+}
+
+func (r *nativeRenderer) indent(delta float64) {
+	left, _, _, _ := r.pdf.GetMargins()
+	r.pdf.SetLeftMargin(left + delta)
+	r.pdf.SetX(left + delta)
+}
+
+func (r *nativeRenderer) listItem(n *html.Node) {
+	r.lineBreak()
+	marker := "• "
+	if len(r.lists) > 0 {
+		l := r.lists[len(r.lists)-1]
+		l.index++
+		if l.ordered {
+			marker = strconv.Itoa(l.index) + ". "
+		}
+	}
+	r.pdf.Write(r.lineHeight(), marker)
+	r.renderChildren(n)
+	r.lineBreak()
+}
+
+func (r *nativeRenderer) table(n *html.Node) {
+	var rows [][]string
+	var headerRows []bool
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "tr" {
+			var cells []string
+			header := false
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.ElementNode && (c.Data == "td" || c.Data == "th") {
+					cells = append(cells, strings.TrimSpace(collapseWhitespace(textContent(c))))
+					header = header || c.Data == "th"
+				}
+			}
+			rows = append(rows, cells)
+			headerRows = append(headerRows, header)
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+
+	cols := 0
+	for _, row := range rows {
+		if len(row) > cols {
+			cols = len(row)
+		}
+	}
+	if cols == 0 {
+		return
+	}
+
+	r.lineBreak()
+	left, _, _, _ := r.pdf.GetMargins()
+	_, pageHeight := r.pdf.GetPageSize()
+	_, _, _, bottom := r.pdf.GetMargins()
+	cellWidth := r.contentWidth() / float64(cols)
+	cellLineHeight := r.lineHeight() - 1
+
+	oldBold := r.bold
+	r.setDrawColor(r.palette.border)
+	r.setFillColor(r.palette.codeBackground)
+	for i, row := range rows {
+		r.bold = headerRows[i]
+		r.applyFont()
+
+		lines := 1
+		for _, cell := range row {
+			if l := len(r.pdf.SplitText(cell, cellWidth-2)); l > lines {
+				lines = l
+			}
+		}
+		rowHeight := float64(lines)*cellLineHeight + 2
+		if r.pdf.GetY()+rowHeight > pageHeight-bottom {
+			r.pdf.AddPage()
+		}
+
+		y := r.pdf.GetY()
+		for j := 0; j < cols; j++ {
+			x := left + float64(j)*cellWidth
+			style := "D"
+			if headerRows[i] {
+				style = "FD"
+			}
+			r.pdf.Rect(x, y, cellWidth, rowHeight, style)
+			if j < len(row) {
+				r.pdf.SetXY(x+1, y+1)
+				restore := r.strokeBold()
+				r.pdf.MultiCell(cellWidth-2, cellLineHeight, row[j], "", "L", false)
+				restore()
+			}
+		}
+		r.pdf.SetXY(left, y+rowHeight)
+	}
+	r.bold = oldBold
+	r.applyFont()
+	r.pdf.Ln(3)
+}
+
+func (r *nativeRenderer) image(src string) {
+	if src == "" {
+		return
+	}
+	data, imageType, err := fetchImage(r.ctx, src)
+	if err != nil {
+		// sometime exists broken image url, just ignore
+		logger.Warnf("Native pdf engine skip image %s, error: %v", src, err)
+		return
+	}
+
+	r.images++
+	name := "img" + strconv.Itoa(r.images)
+	opt := fpdf.ImageOptions{ImageType: imageType, ReadDpi: true}
+	info := r.pdf.RegisterImageOptionsReader(name, opt, bytes.NewReader(data))
+	if r.pdf.Err() || info == nil {
+		logger.Warnf("Native pdf engine skip image %s, error: %v", src, r.pdf.Error())
+		r.pdf.ClearError()
+		return
+	}
+
+	r.lineBreak()
+	w, h := info.Width(), info.Height()
+	if maxWidth := r.contentWidth(); w > maxWidth {
+		h = h * maxWidth / w
+		w = maxWidth
+	}
+	_, pageHeight := r.pdf.GetPageSize()
+	_, top, _, bottom := r.pdf.GetMargins()
+	if maxHeight := pageHeight - top - bottom; h > maxHeight {
+		w = w * maxHeight / h
+		h = maxHeight
+	}
+	if r.pdf.GetY()+h > pageHeight-bottom {
+		r.pdf.AddPage()
+	}
+	left, _, _, _ := r.pdf.GetMargins()
+	r.pdf.ImageOptions(name, left, r.pdf.GetY(), w, h, true, opt, 0, "")
+	r.pdf.Ln(2)
+}
+
+// imageClient has timeout, so that a stalled image does not hang rendering
+var imageClient = &http.Client{Timeout: geektime.DefaultTimeout}
+
+func fetchImage(ctx context.Context, imageURL string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set(geektime.Origin, geektime.DefaultBaseURL)
+	req.Header.Set(geektime.UserAgent, geektime.DefaultUserAgent)
+
+	resp, err := imageClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch http.DetectContentType(data) {
+	case "image/png":
+		return data, "PNG", nil
+	case "image/jpeg":
+		return data, "JPG", nil
+	case "image/gif":
+		return data, "GIF", nil
+	case "image/webp":
+		// fpdf does not support webp, convert it to png
+		img, err := webp.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, "", err
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "PNG", nil
+	default:
+		return nil, "", errors.New("unsupported image format")
+	}
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "br" {
+			sb.WriteString("\n")
+			continue
+		}
+		sb.WriteString(textContent(c))
+	}
+	return sb.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// collapseWhitespace replace runs of white space with a single space like browsers do
+func collapseWhitespace(s string) string {
+	return whitespaceRegexp.ReplaceAllString(s, " ")
+}
@@ -0,0 +1,78 @@
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/net/html"
+)
+
+// 1x1 lossless webp
+var webpImage = []byte("RIFF\x1a\x00\x00\x00WEBPVP8L\x0d\x00\x00\x00\x2f\x00\x00\x00\x10\x07\x10\x11\x11\x88\x88\xfe\x07\x00")
+
+func TestNativeRender(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(webpImage)
+	}))
+	defer srv.Close()
+
+	doc, err := html.Parse(strings.NewReader(`<p>plain <strong>bold</strong> <em>italic ` +
+		strings.Repeat("wrapped words ", 30) + `</em></p><img src="` + srv.URL + `/a.webp">`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newNativeRenderer(context.Background(), goregular.TTF, DefaultPageLayout(), "title")
+	r.pdf.SetCompression(false)
+	r.renderChildren(doc)
+	var buf bytes.Buffer
+	if err := r.pdf.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	if !strings.Contains(out, "2 Tr") {
+		t.Error("bold text is not stroked")
+	}
+	// one transform for the image, others for italic lines
+	if strings.Count(out, " cm") < 3 {
+		t.Error("wrapped italic text is not skewed line by line")
+	}
+	if !strings.Contains(out, "/Subtype /Image") {
+		t.Error("webp image is not embedded")
+	}
+}
+
+func TestNativeRenderRestoresStyle(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<p><a href="https://time.geekbang.org"><code>c</code>tail</a></p>` +
+		`<strong><table><tr><td>cell</td></tr></table>after</strong>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newNativeRenderer(context.Background(), goregular.TTF, DefaultPageLayout(), "title")
+	r.pdf.SetCompression(false)
+	r.renderChildren(doc)
+	var buf bytes.Buffer
+	if err := r.pdf.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	// text of ascii glyphs is written as big endian utf-16
+	m := regexp.MustCompile(`q ([\d. ]+) (?:rg|g) BT [^(]*\(\x00t\x00a\x00i\x00l\)`).FindStringSubmatch(out)
+	if m == nil || m[1] != "0.012 0.400 0.839" {
+		t.Errorf("text after code in link is not link color: %q", m)
+	}
+	after := strings.Index(out, "(\x00a\x00f\x00t\x00e\x00r)")
+	if after < 0 {
+		t.Fatal("text after table not found")
+	}
+	if mode := strings.LastIndex(out[:after], " Tr"); mode < 1 || out[mode-1] != '2' {
+		t.Error("text after table in strong is not bold")
+	}
+}
@@ -15,33 +15,37 @@ import (
 	"github.com/chromedp/cdproto/page"
 	"github.com/chromedp/cdproto/runtime"
 	"github.com/chromedp/chromedp"
-	"github.com/chromedp/chromedp/device"
 	"github.com/nicoxiang/geektime-downloader/internal/geektime"
-	"github.com/nicoxiang/geektime-downloader/internal/pkg/filenamify"
 	"github.com/nicoxiang/geektime-downloader/internal/pkg/files"
 )
 
 // PDFExtension ...
 const PDFExtension = ".pdf"
 
-// PrintArticlePageToPDF use chromedp to print article page and save
+// PrintArticlePageToPDF use chromedp to print article page and save as name in dir, name may
+// contain sub dirs
 func PrintArticlePageToPDF(ctx context.Context,
 	aid int,
 	dir,
+	name,
 	title string,
 	cookies []*http.Cookie,
 	downloadComments bool,
 	printPDFWaitSeconds int,
 	printPDFTimeoutSeconds int,
+	layout PageLayout,
 	overwrite bool,
 ) (bool, error) {
 	rateLimit := false
 
-	fileName := filepath.Join(dir, filenamify.Filenamify(title)+PDFExtension)
+	fileName := filepath.Join(dir, name+PDFExtension)
 
 	if files.CheckFileExists(fileName) && !overwrite {
 		return true, nil
 	}
+	if err := os.MkdirAll(filepath.Dir(fileName), os.ModePerm); err != nil {
+		return false, err
+	}
 
 	// new tab
 	ctx, cancel := chromedp.NewContext(ctx)
@@ -63,12 +67,13 @@ func PrintArticlePageToPDF(ctx context.Context,
 
 	err := chromedp.Run(ctx,
 		chromedp.Tasks{
-			chromedp.Emulate(device.IPadPro11),
+			emulate(layout.Device),
 			setCookies(cookies),
 			chromedp.Navigate(geektime.DefaultBaseURL + `/column/article/` + strconv.Itoa(aid)),
 			chromedp.Sleep(time.Duration(printPDFWaitSeconds) * time.Second),
 			hideRedundantElements(downloadComments),
-			printToPDF(fileName),
+			applyTheme(layout.Theme),
+			printToPDF(fileName, title, layout),
 		},
 	)
 
@@ -82,6 +87,13 @@ func PrintArticlePageToPDF(ctx context.Context,
 	return false, nil
 }
 
+func emulate(deviceName string) chromedp.Action {
+	if d, ok := devices[deviceName]; ok {
+		return chromedp.Emulate(d)
+	}
+	return chromedp.EmulateViewport(1280, 1024)
+}
+
 func setCookies(cookies []*http.Cookie) chromedp.ActionFunc {
 	return chromedp.ActionFunc(func(ctx context.Context) error {
 		expr := cdp.TimeSinceEpoch(time.Now().Add(180 * 24 * time.Hour))
@@ -178,15 +190,58 @@ func hideRedundantElements(downloadComments bool) chromedp.ActionFunc {
 	})
 }
 
-func printToPDF(fileName string) chromedp.ActionFunc {
+// applyTheme invert page colors for dark theme, images and videos are inverted back
+func applyTheme(theme string) chromedp.ActionFunc {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if theme != ThemeDark {
+			return nil
+		}
+		s :=
+			`
+			var themeStyle = document.createElement('style');
+			themeStyle.innerHTML = 'html{background:#fff;filter:invert(1) hue-rotate(180deg);} img,video,svg{filter:invert(1) hue-rotate(180deg);}';
+			document.head.appendChild(themeStyle);
+		`
+		_, exp, err := runtime.Evaluate(s).Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		if exp != nil {
+			return exp
+		}
+
+		return nil
+	})
+}
+
+func printToPDF(fileName, title string, layout PageLayout) chromedp.ActionFunc {
 	return chromedp.ActionFunc(func(ctx context.Context) error {
-		_, stream, err := page.PrintToPDF().
-			WithMarginTop(0.4).
-			WithMarginBottom(0.4).
-			WithMarginLeft(0.4).
-			WithMarginRight(0.4).
-			WithTransferMode(page.PrintToPDFTransferModeReturnAsStream).
-			Do(ctx)
+		params := page.PrintToPDF().
+			WithMarginTop(layout.Margins[0] / mmPerInch).
+			WithMarginRight(layout.Margins[1] / mmPerInch).
+			WithMarginBottom(layout.Margins[2] / mmPerInch).
+			WithMarginLeft(layout.Margins[3] / mmPerInch).
+			WithLandscape(layout.Landscape).
+			WithScale(layout.Scale).
+			// dark theme needs background colors
+			WithPrintBackground(layout.PrintBackground || layout.Theme == ThemeDark).
+			WithTransferMode(page.PrintToPDFTransferModeReturnAsStream)
+
+		if layout.PaperWidth > 0 && layout.PaperHeight > 0 {
+			params = params.
+				WithPaperWidth(layout.PaperWidth / mmPerInch).
+				WithPaperHeight(layout.PaperHeight / mmPerInch)
+		}
+
+		if layout.hasHeaderFooter() {
+			params = params.
+				WithDisplayHeaderFooter(true).
+				WithHeaderTemplate(chromeTemplate(layout.HeaderTemplate, title)).
+				WithFooterTemplate(chromeTemplate(layout.FooterTemplate, title))
+		}
+
+		_, stream, err := params.Do(ctx)
 		if err != nil {
 			return err
 		}
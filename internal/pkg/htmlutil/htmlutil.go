@@ -0,0 +1,178 @@
+package htmlutil
+
+import (
+	"context"
+	"html"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/nicoxiang/geektime-downloader/internal/geektime"
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/images"
+	nethtml "golang.org/x/net/html"
+)
+
+// ParseBody parse article html and return its body node
+func ParseBody(articleHTML string) (*nethtml.Node, error) {
+	doc, err := nethtml.Parse(strings.NewReader(articleHTML))
+	if err != nil {
+		return nil, err
+	}
+	if body := FindElement(doc, "body"); body != nil {
+		return body, nil
+	}
+	return doc, nil
+}
+
+// AbsolutizeLinks resolve site relative links against geektime, they do not exist locally
+func AbsolutizeLinks(n *nethtml.Node) {
+	if n.Type == nethtml.ElementNode && n.Data == "a" {
+		href := Attr(n, "href")
+		if u, err := url.Parse(href); err == nil && u.Scheme == "" && u.Path != "" {
+			base, _ := url.Parse(geektime.DefaultBaseURL)
+			SetAttr(n, "href", base.ResolveReference(u).String())
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		AbsolutizeLinks(c)
+	}
+}
+
+// RewriteLinks replace href of links with result of rewrite if ok
+func RewriteLinks(n *nethtml.Node, rewrite func(href string) (string, bool)) {
+	if n.Type == nethtml.ElementNode && n.Data == "a" {
+		if href, ok := rewrite(Attr(n, "href")); ok {
+			SetAttr(n, "href", href)
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		RewriteLinks(c, rewrite)
+	}
+}
+
+// LocalizeImages download remote images to dir/images shared by course and rewrite src to the
+// relative path, images failed to download are replaced with placeholder
+func LocalizeImages(ctx context.Context, root *nethtml.Node, dir string) error {
+	var imgs []*nethtml.Node
+	var urls []string
+	var walk func(n *nethtml.Node)
+	walk = func(n *nethtml.Node) {
+		if n.Type == nethtml.ElementNode && n.Data == "img" {
+			imgs = append(imgs, n)
+			urls = append(urls, Attr(n, "src"))
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+
+	var remote []string
+	for i, img := range imgs {
+		u, err := url.Parse(urls[i])
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			img.Parent.RemoveChild(img)
+			continue
+		}
+		remote = append(remote, urls[i])
+	}
+	if len(remote) == 0 {
+		return nil
+	}
+	names, err := images.NewFetcher(filepath.Join(dir, "images")).Fetch(ctx, remote)
+	if err != nil {
+		return err
+	}
+	for i, img := range imgs {
+		if name, ok := names[urls[i]]; ok {
+			SetAttr(img, "src", "images/"+name)
+		}
+	}
+	return nil
+}
+
+// CommentsHTML render comments as html fragment, which is also well-formed xhtml
+func CommentsHTML(comments []geektime.Comment) string {
+	var sb strings.Builder
+	for _, c := range comments {
+		sb.WriteString(`<div class="comment">` + "\n")
+		sb.WriteString(`<p class="comment-meta"><strong>` + html.EscapeString(c.DisplayName()) + "</strong> · " + c.Date())
+		if c.LikeCount > 0 {
+			sb.WriteString(" · 赞 " + strconv.Itoa(c.LikeCount))
+		}
+		sb.WriteString("</p>\n" + paragraphs(c.Content))
+		for _, r := range c.Replies {
+			sb.WriteString(`<blockquote class="reply">` + "\n")
+			sb.WriteString(`<p class="comment-meta"><strong>` + html.EscapeString(r.DisplayName()) + "</strong> · " + r.Date() + "</p>\n")
+			sb.WriteString(paragraphs(r.Content) + "</blockquote>\n")
+		}
+		sb.WriteString("</div>\n")
+	}
+	return sb.String()
+}
+
+func paragraphs(s string) string {
+	var sb strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(s), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			sb.WriteString("<p>" + html.EscapeString(line) + "</p>\n")
+		}
+	}
+	return sb.String()
+}
+
+// FindElement find the first element with name in n and its descendants
+func FindElement(n *nethtml.Node, name string) *nethtml.Node {
+	if n.Type == nethtml.ElementNode && n.Data == name {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := FindElement(c, name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// Attr get attribute value of element
+func Attr(n *nethtml.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// SetAttr set attribute value of element
+func SetAttr(n *nethtml.Node, key, val string) {
+	for i, a := range n.Attr {
+		if a.Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, nethtml.Attribute{Key: key, Val: val})
+}
+
+// RemoveElements remove elements with given names and their children
+func RemoveElements(n *nethtml.Node, names ...string) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		removed := false
+		if c.Type == nethtml.ElementNode {
+			for _, name := range names {
+				if c.Data == name {
+					n.RemoveChild(c)
+					removed = true
+					break
+				}
+			}
+		}
+		if !removed {
+			RemoveElements(c, names...)
+		}
+		c = next
+	}
+}
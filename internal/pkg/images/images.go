@@ -0,0 +1,323 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nicoxiang/geektime-downloader/internal/geektime"
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/files"
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/logger"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// ManifestFileName record url to file name of downloaded images in store dir
+	ManifestFileName = ".images.json"
+	// PlaceholderFileName is image used in place of images failed to download
+	PlaceholderFileName = "placeholder.png"
+	// Concurrency is default number of images downloaded at the same time
+	Concurrency = 4
+
+	attempts   = 3
+	retrySleep = 500 * time.Millisecond
+	maxSize    = 32 << 20
+)
+
+var (
+	failuresMu sync.Mutex
+	failures   []Failure
+
+	errNotImage = errors.New("不是图片")
+	errTooLarge = fmt.Errorf("图片超过 %d MB", maxSize>>20)
+
+	// client has timeout, so that a stalled image does not hang the export
+	client = &http.Client{Timeout: geektime.DefaultTimeout}
+)
+
+// Failure is an image failed to download, placeholder is used instead
+type Failure struct {
+	URL string
+	Err error
+}
+
+// Fetcher download images into store dir, images are named by hash of content so that same
+// image referenced by different urls or articles is saved only once
+type Fetcher struct {
+	dir         string
+	concurrency int
+	opts        Options
+
+	mu       sync.Mutex
+	manifest map[string]string
+}
+
+// NewFetcher create fetcher of store dir processing images with DefaultOptions, urls downloaded
+// before are loaded from manifest
+func NewFetcher(dir string) *Fetcher {
+	f := &Fetcher{dir: dir, concurrency: Concurrency, opts: DefaultOptions, manifest: make(map[string]string)}
+	if data, err := os.ReadFile(filepath.Join(dir, ManifestFileName)); err == nil {
+		_ = json.Unmarshal(data, &f.manifest)
+	}
+	return f
+}
+
+// Fetch download images of urls concurrently and return file name in store dir of each url.
+// Images failed to download are mapped to placeholder and recorded as failures, error is only
+// returned if ctx is done or store dir is not writable
+func (f *Fetcher) Fetch(ctx context.Context, urls []string) (map[string]string, error) {
+	if err := os.MkdirAll(f.dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	result := make(map[string]string, len(urls))
+	var resultMu sync.Mutex
+	seen := make(map[string]bool, len(urls))
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(f.concurrency)
+	for _, u := range urls {
+		if seen[u] {
+			continue
+		}
+		seen[u] = true
+		if name, ok := f.cached(u); ok {
+			result[u] = name
+			continue
+		}
+		u := u
+		g.Go(func() error {
+			name, err := f.fetch(ctx, u)
+			if err != nil {
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					return ctxErr
+				}
+				logger.Warnf("Download image %s failed: %v", u, err)
+				addFailure(Failure{URL: u, Err: err})
+				if name, err = f.placeholder(); err != nil {
+					return err
+				}
+			} else {
+				f.mu.Lock()
+				f.manifest[f.manifestKey(u)] = name
+				f.mu.Unlock()
+			}
+			resultMu.Lock()
+			result[u] = name
+			resultMu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return result, f.saveManifest()
+}
+
+// cached return file name of url downloaded before if file still exists
+func (f *Fetcher) cached(u string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	name, ok := f.manifest[f.manifestKey(u)]
+	if ok && files.CheckFileExists(filepath.Join(f.dir, name)) {
+		return name, true
+	}
+	return "", false
+}
+
+// manifestKey of url, images processed with other options are not reused
+func (f *Fetcher) manifestKey(u string) string {
+	if key := f.opts.key(); key != "" {
+		return u + " " + key
+	}
+	return u
+}
+
+// fetch download image with retry on transient errors, process it with options and save it by
+// hash of original content
+func (f *Fetcher) fetch(ctx context.Context, u string) (string, error) {
+	var data []byte
+	var err error
+	sleep := retrySleep
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-time.After(sleep):
+			}
+			sleep *= 2
+		}
+		var retryable bool
+		data, retryable, err = get(ctx, u)
+		if err == nil || !retryable {
+			break
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	ext := Sniff(data, u)
+	if ext == "" {
+		return "", errNotImage
+	}
+	h := sha256.New()
+	h.Write(data)
+	h.Write([]byte(f.opts.key()))
+	data, ext = f.opts.process(data, ext, u)
+	name := hex.EncodeToString(h.Sum(nil)[:16]) + ext
+	return name, writeFile(filepath.Join(f.dir, name), data)
+}
+
+// get request url, network errors, 429 and 5xx responses are retryable
+func get(ctx context.Context, u string) ([]byte, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set(geektime.Origin, geektime.DefaultBaseURL)
+	req.Header.Set(geektime.UserAgent, geektime.DefaultUserAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return nil, retryable, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, true, err
+	}
+	if len(data) > maxSize {
+		return nil, false, errTooLarge
+	}
+	return data, false, nil
+}
+
+// Sniff return file extension of image data, extension of url is used only if content can not be
+// detected, empty string is returned if data is not an image
+func Sniff(data []byte, u string) string {
+	switch http.DetectContentType(data) {
+	case "image/png":
+		return ".png"
+	case "image/jpeg":
+		return ".jpg"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	case "image/bmp":
+		return ".bmp"
+	case "text/xml; charset=utf-8", "text/plain; charset=utf-8":
+		head := data
+		if len(head) > 1024 {
+			head = head[:1024]
+		}
+		if bytes.Contains(head, []byte("<svg")) {
+			return ".svg"
+		}
+		return ""
+	case "application/octet-stream":
+		if p, err := url.Parse(u); err == nil {
+			switch ext := strings.ToLower(path.Ext(p.Path)); ext {
+			case ".jpg", ".jpeg", ".png", ".gif", ".webp", ".bmp", ".tiff", ".svg":
+				return ext
+			}
+		}
+	}
+	return ""
+}
+
+// placeholder write placeholder image to store dir if not exists
+func (f *Fetcher) placeholder() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p := filepath.Join(f.dir, PlaceholderFileName)
+	if files.CheckFileExists(p) {
+		return PlaceholderFileName, nil
+	}
+	const w, h = 320, 180
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.Gray{Y: 0xee}
+			if x < 2 || y < 2 || x >= w-2 || y >= h-2 || x*h == y*w || x*h == (h-1-y)*w {
+				c = color.Gray{Y: 0xaa}
+			}
+			img.SetGray(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", err
+	}
+	return PlaceholderFileName, writeFile(p, buf.Bytes())
+}
+
+func (f *Fetcher) saveManifest() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.manifest) == 0 {
+		return nil
+	}
+	data, err := json.MarshalIndent(f.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(f.dir, ManifestFileName), data)
+}
+
+// writeFile write data to a temp file first so that partial files are never left in store dir
+func writeFile(name string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+func addFailure(f Failure) {
+	failuresMu.Lock()
+	defer failuresMu.Unlock()
+	failures = append(failures, f)
+}
+
+// TakeFailures return images failed to download since last call
+func TakeFailures() []Failure {
+	failuresMu.Lock()
+	defer failuresMu.Unlock()
+	result := failures
+	failures = nil
+	return result
+}
@@ -0,0 +1,87 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/images/imagestest"
+)
+
+func TestFetch(t *testing.T) {
+	srv := imagestest.NewServer(t)
+	TakeFailures()
+
+	dir := t.TempDir()
+	urls := []string{srv.URL + "/a.png", srv.URL + "/noext", srv.URL + "/flaky.png", srv.URL + "/missing.png", srv.URL + "/page.png"}
+	names, err := NewFetcher(dir).Fetch(context.Background(), urls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names[urls[0]] != names[urls[1]] || names[urls[0]] != names[urls[2]] || filepath.Ext(names[urls[0]]) != ".png" {
+		t.Errorf("same content should share one file: %v", names)
+	}
+	if names[urls[3]] != PlaceholderFileName || names[urls[4]] != PlaceholderFileName {
+		t.Errorf("broken images should use placeholder: %v", names)
+	}
+	if failures := TakeFailures(); len(failures) != 2 {
+		t.Errorf("got %d failures, want 2", len(failures))
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 3 {
+		t.Errorf("got %d files in store, want image, placeholder and manifest", len(entries))
+	}
+
+	// downloaded urls are loaded from manifest
+	srv.Close()
+	names, err = NewFetcher(dir).Fetch(context.Background(), urls[:1])
+	if err != nil || names[urls[0]] == PlaceholderFileName {
+		t.Errorf("downloaded image should be reused: %v %v", names, err)
+	}
+}
+
+func TestGetTooLarge(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.CopyN(w, zeroReader{}, maxSize+1)
+	}))
+	defer srv.Close()
+	// oversized image fails instead of being truncated
+	if _, retryable, err := get(context.Background(), srv.URL+"/large.png"); err != errTooLarge || retryable {
+		t.Errorf("got %v retryable %t, want %v", err, retryable, errTooLarge)
+	}
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestProcess(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 200, 100))); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// size hint says image is narrow enough, it is kept without decoding
+	if out, ext := (Options{MaxWidth: 100}).process(data, ".png", "https://example.com/a.png?wh=80x40"); !bytes.Equal(out, data) || ext != ".png" {
+		t.Error("image should be kept by size hint")
+	}
+	out, ext := (Options{MaxWidth: 100}).process(data, ".png", "https://example.com/a.png")
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(out))
+	if err != nil || ext != ".png" || cfg.Width != 100 || cfg.Height != 50 {
+		t.Errorf("got %s %dx%d %v, want .png 100x50", ext, cfg.Width, cfg.Height, err)
+	}
+	if w, ok := sizeHint("https://static001.geekbang.org/resource/image/21/3b/x.png?wh=1856x534"); !ok || w != 1856 {
+		t.Errorf("got size hint %d %t, want 1856", w, ok)
+	}
+}
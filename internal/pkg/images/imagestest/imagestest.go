@@ -0,0 +1,57 @@
+// Package imagestest provides an image server for tests of packages downloading images.
+package imagestest
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Server serves the same PNG at every path, except paths starting with /missing which are not
+// found, /page which return html and /flaky which fail with 502 on the first request
+type Server struct {
+	*httptest.Server
+	image []byte
+
+	mu    sync.Mutex
+	flaky map[string]bool
+}
+
+// NewServer start image server closed when test finishes
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{image: buf.Bytes(), flaky: make(map[string]bool)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	switch p := r.URL.Path; {
+	case strings.HasPrefix(p, "/missing"):
+		http.NotFound(w, r)
+	case strings.HasPrefix(p, "/page"):
+		_, _ = w.Write([]byte("<html></html>"))
+	case strings.HasPrefix(p, "/flaky") && s.firstRequest(p):
+		w.WriteHeader(http.StatusBadGateway)
+	default:
+		_, _ = w.Write(s.image)
+	}
+}
+
+func (s *Server) firstRequest(p string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	first := !s.flaky[p]
+	s.flaky[p] = true
+	return first
+}
@@ -0,0 +1,128 @@
+package images
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/logger"
+	"golang.org/x/image/draw"
+	// register webp decoder
+	_ "golang.org/x/image/webp"
+)
+
+// defaultQuality is jpeg quality of resized images if quality is not set
+const defaultQuality = 90
+
+// Options of image post-processing, zero value keeps images as downloaded
+type Options struct {
+	// MaxWidth scale down images wider than it, 0 means no limit
+	MaxWidth int
+	// Quality recompress jpeg with this quality and png with best compression, 0 means no recompress
+	Quality int
+	// WebPToPNG convert webp to png for e-readers which do not support webp
+	WebPToPNG bool
+}
+
+// DefaultOptions is used by fetchers created by NewFetcher
+var DefaultOptions Options
+
+// Validate ...
+func (o Options) Validate() error {
+	if o.MaxWidth < 0 {
+		return fmt.Errorf("图片最大宽度不能小于 0: %d", o.MaxWidth)
+	}
+	if o.Quality < 0 || o.Quality > 100 {
+		return fmt.Errorf("图片压缩质量需在 1 - 100 之间: %d", o.Quality)
+	}
+	return nil
+}
+
+// key distinguish images processed with different options in manifest and store
+func (o Options) key() string {
+	if o == (Options{}) {
+		return ""
+	}
+	return fmt.Sprintf("w%d-q%d-png%t", o.MaxWidth, o.Quality, o.WebPToPNG)
+}
+
+// process resize, recompress or convert image, original image is returned if nothing needs to be
+// done or processing failed. Size hint in url is used to skip decoding images narrow enough.
+func (o Options) process(data []byte, ext, u string) ([]byte, string) {
+	if o == (Options{}) {
+		return data, ext
+	}
+	convert := o.WebPToPNG && ext == ".webp"
+	recompress := o.Quality > 0 && (ext == ".jpg" || ext == ".png")
+	resize := false
+	if o.MaxWidth > 0 && (ext == ".jpg" || ext == ".png" || ext == ".webp") {
+		width, ok := sizeHint(u)
+		if !ok {
+			if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+				width, ok = cfg.Width, true
+			}
+		}
+		resize = ok && width > o.MaxWidth
+	}
+	if !convert && !recompress && !resize {
+		return data, ext
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		logger.Warnf("Decode image %s failed, keep original: %v", u, err)
+		return data, ext
+	}
+	resized := false
+	if b := img.Bounds(); o.MaxWidth > 0 && b.Dx() > o.MaxWidth {
+		height := b.Dy() * o.MaxWidth / b.Dx()
+		if height < 1 {
+			height = 1
+		}
+		dst := image.NewRGBA(image.Rect(0, 0, o.MaxWidth, height))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+		img = dst
+		resized = true
+	}
+
+	var buf bytes.Buffer
+	outExt := ext
+	if ext == ".jpg" {
+		quality := o.Quality
+		if quality == 0 {
+			quality = defaultQuality
+		}
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	} else {
+		// there is no webp encoder, resized webp is saved as png too
+		outExt = ".png"
+		err = (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(&buf, img)
+	}
+	if err != nil {
+		logger.Warnf("Encode image %s failed, keep original: %v", u, err)
+		return data, ext
+	}
+	if !resized && outExt == ext && buf.Len() >= len(data) {
+		return data, ext
+	}
+	return buf.Bytes(), outExt
+}
+
+// sizeHint return width in wh query of geektime image url, e.g. ?wh=1856x534
+func sizeHint(u string) (int, bool) {
+	p, err := url.Parse(u)
+	if err != nil {
+		return 0, false
+	}
+	w, _, ok := strings.Cut(p.Query().Get("wh"), "x")
+	if !ok {
+		return 0, false
+	}
+	width, err := strconv.Atoi(w)
+	return width, err == nil && width > 0
+}
@@ -0,0 +1,251 @@
+package podcast
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nicoxiang/geektime-downloader/internal/audio"
+	"github.com/nicoxiang/geektime-downloader/internal/markdown"
+)
+
+const (
+	// FeedFileName ...
+	FeedFileName = "feed.xml"
+
+	itunesNamespace   = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+	descriptionLength = 300
+)
+
+// ErrNoAudio ...
+var ErrNoAudio = errors.New("目录中没有 MP3 音频文件")
+
+type rss struct {
+	XMLName xml.Name `xml:"rss"`
+	Version string   `xml:"version,attr"`
+	Itunes  string   `xml:"xmlns:itunes,attr"`
+	Channel channel  `xml:"channel"`
+}
+
+type channel struct {
+	Title       string       `xml:"title"`
+	Link        string       `xml:"link"`
+	Description string       `xml:"description"`
+	Language    string       `xml:"language"`
+	Author      string       `xml:"itunes:author,omitempty"`
+	Image       *itunesImage `xml:"itunes:image,omitempty"`
+	Explicit    string       `xml:"itunes:explicit"`
+	Items       []item       `xml:"item"`
+}
+
+type itunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+type item struct {
+	Title       string    `xml:"title"`
+	Description string    `xml:"description"`
+	Enclosure   enclosure `xml:"enclosure"`
+	GUID        string    `xml:"guid"`
+	PubDate     string    `xml:"pubDate"`
+	Episode     int       `xml:"itunes:episode,omitempty"`
+}
+
+type enclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// WriteFeed generate RSS 2.0 podcast feed with iTunes tags for mp3 files in audio dir,
+// episode description is article summary saved in mp3 comment, or the first paragraphs of
+// markdown with same name in mdDir, laid out by flavor, for mp3 without summary. Enclosure urls are relative to baseURL, feed is written to audio dir and
+// its path returned.
+func WriteFeed(dir, mdDir string, flavor markdown.Flavor, baseURL string) (string, error) {
+	files, err := audio.ReadCourseDir(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", ErrNoAudio
+	}
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+
+	var album, author string
+	for _, f := range files {
+		if album == "" {
+			album = f.Album
+		}
+		if author == "" {
+			author = f.Author
+		}
+	}
+	if album == "" {
+		album = filepath.Base(dir)
+	}
+
+	feed := rss{
+		Version: "2.0",
+		Itunes:  itunesNamespace,
+		Channel: channel{
+			Title:       album,
+			Link:        baseURL,
+			Description: album,
+			Language:    "zh-cn",
+			Author:      author,
+			Explicit:    "false",
+		},
+	}
+	if cover := writeCover(dir, files); cover != "" {
+		feed.Channel.Image = &itunesImage{Href: baseURL + url.PathEscape(cover)}
+	}
+
+	// podcast players order episodes by publish date, so dates are
+	// increased one minute per episode to keep the course order
+	base := files[0].ModTime
+	for _, f := range files {
+		if f.ModTime.Before(base) {
+			base = f.ModTime
+		}
+	}
+	for i, f := range files {
+		rel, _ := filepath.Rel(dir, f.Path)
+		link := baseURL + escapePath(rel)
+		description := truncate(strings.TrimSpace(f.Summary))
+		if description == "" {
+			description = markdownSummary(flavor.ArticlePath(mdDir, f.Name))
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item{
+			Title:       f.Title,
+			Description: description,
+			Enclosure:   enclosure{URL: link, Length: f.Size, Type: "audio/mpeg"},
+			GUID:        link,
+			PubDate:     base.Add(time.Duration(i) * time.Minute).Format(time.RFC1123Z),
+			Episode:     f.Track,
+		})
+	}
+
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	fileName := filepath.Join(dir, FeedFileName)
+	if err := os.WriteFile(fileName, append([]byte(xml.Header), data...), 0644); err != nil {
+		return "", err
+	}
+	return fileName, nil
+}
+
+// escapePath escape every segment of path relative to course dir for url
+func escapePath(p string) string {
+	segments := strings.Split(filepath.ToSlash(p), "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// writeCover save embedded cover art of mp3 files into dir, return file name
+func writeCover(dir string, files []audio.File) string {
+	cover, mimeType := audio.ReadCover(files)
+	if len(cover) == 0 {
+		return ""
+	}
+	name := "cover.jpg"
+	if strings.Contains(mimeType, "png") {
+		name = "cover.png"
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), cover, 0644); err != nil {
+		return ""
+	}
+	return name
+}
+
+// markdownSummary return first paragraphs of markdown article, headings, images and code are skipped
+func markdownSummary(fileName string) string {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	var sb strings.Builder
+	inCode := false
+	// front matter is only allowed at the first line
+	inFrontMatter := false
+	lineNo := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		lineNo++
+		if lineNo == 1 && line == "---" {
+			inFrontMatter = true
+			continue
+		}
+		if inFrontMatter {
+			inFrontMatter = line != "---"
+			continue
+		}
+		if strings.HasPrefix(line, "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode || line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "![") ||
+			strings.HasPrefix(line, "---") {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(line)
+		if len([]rune(sb.String())) >= descriptionLength {
+			break
+		}
+	}
+	return truncate(sb.String())
+}
+
+// truncate cut description longer than descriptionLength
+func truncate(s string) string {
+	r := []rune(s)
+	if len(r) > descriptionLength {
+		return string(r[:descriptionLength]) + "…"
+	}
+	return s
+}
+
+// LANAddress return first non loopback ipv4 address, used to build feed url for devices in LAN
+func LANAddress() string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "127.0.0.1"
+	}
+	for _, a := range addrs {
+		if ipNet, ok := a.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+			return ipNet.IP.String()
+		}
+	}
+	return "127.0.0.1"
+}
+
+// BaseURL build http base url with LAN address and port of listen address like ":8090"
+func BaseURL(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("监听地址格式不合法: %s", addr)
+	}
+	if host == "" || host == "0.0.0.0" {
+		host = LANAddress()
+	}
+	return "http://" + net.JoinHostPort(host, port) + "/", nil
+}
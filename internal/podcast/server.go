@@ -0,0 +1,38 @@
+package podcast
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/logger"
+)
+
+// Serve serve feed and audio files in dir until ctx is done, range requests are supported
+func Serve(ctx context.Context, dir, addr string) error {
+	fileServer := http.FileServer(http.Dir(dir))
+	srv := &http.Server{
+		Addr: addr,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger.Infof("Podcast request, method: %s, url: %s, remote: %s", r.Method, r.URL, r.RemoteAddr)
+			if r.URL.Path == "/"+FeedFileName {
+				w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+			}
+			fileServer.ServeHTTP(w, r)
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
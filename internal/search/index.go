@@ -0,0 +1,249 @@
+package search
+
+import (
+	"encoding/gob"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nicoxiang/geektime-downloader/internal/markdown"
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/files"
+)
+
+const (
+	// IndexFileName is search index saved in download folder
+	IndexFileName = ".search.idx"
+
+	indexVersion = 1
+	markdownDir  = "markdown"
+	// comments are appended to markdown under this heading
+	commentsHeading = "\n## 精选留言\n"
+)
+
+type field uint8
+
+const (
+	fieldCourse field = iota
+	fieldTitle
+	fieldBody
+	fieldComments
+	numFields
+)
+
+var fieldNames = [numFields]string{"course", "title", "body", "comments"}
+
+// Doc is one indexed markdown article
+type Doc struct {
+	// Path is relative to download folder
+	Path    string
+	Course  string
+	Title   string
+	ModTime int64
+	Size    int64
+	Lengths [numFields]int
+}
+
+// Posting is positions of term in one field of doc
+type Posting struct {
+	Doc       int
+	Field     field
+	Positions []int
+}
+
+// Index is inverted index of downloaded markdown articles
+type Index struct {
+	Version int
+	Docs    []Doc
+	Terms   map[string][]Posting
+}
+
+// Exists report whether search index is built in root
+func Exists(root string) bool {
+	return files.CheckFileExists(filepath.Join(root, IndexFileName))
+}
+
+// Load read search index of root, an empty index is returned if not built yet or built by
+// another version
+func Load(root string) (*Index, error) {
+	idx := &Index{Version: indexVersion, Terms: make(map[string][]Posting)}
+	f, err := os.Open(filepath.Join(root, IndexFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var loaded Index
+	if err := gob.NewDecoder(f).Decode(&loaded); err != nil || loaded.Version != indexVersion {
+		return idx, nil
+	}
+	if loaded.Terms == nil {
+		loaded.Terms = make(map[string][]Posting)
+	}
+	return &loaded, nil
+}
+
+// Save write index to root atomically
+func (idx *Index) Save(root string) error {
+	tmp := filepath.Join(root, IndexFileName+".tmp")
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(f).Encode(idx); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(root, IndexFileName))
+}
+
+// UpdateStats is result of an incremental update
+type UpdateStats struct {
+	Added   int
+	Updated int
+	Removed int
+	Total   int
+}
+
+// Update index markdown articles under root/markdown incrementally, only new and modified files are
+// read, postings of removed and modified files are dropped
+func Update(root string, rebuild bool) (UpdateStats, error) {
+	var stats UpdateStats
+	idx, err := Load(root)
+	if err != nil {
+		return stats, err
+	}
+	if rebuild {
+		idx = &Index{Version: indexVersion, Terms: make(map[string][]Posting)}
+	}
+
+	current := make(map[string]fs.FileInfo)
+	base := filepath.Join(root, markdownDir)
+	err = filepath.WalkDir(base, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == base && errors.Is(err, fs.ErrNotExist) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == "images" || d.Name() == "comments" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.EqualFold(filepath.Ext(p), ".md") || markdown.IsCourseIndex(d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, p)
+		current[filepath.ToSlash(rel)] = info
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	// keep unchanged docs, renumber them and drop postings of others in one pass
+	ids := make([]int, len(idx.Docs))
+	var kept []Doc
+	for i, d := range idx.Docs {
+		info, ok := current[d.Path]
+		switch {
+		case !ok:
+			ids[i] = -1
+			stats.Removed++
+		case info.ModTime().UnixNano() != d.ModTime || info.Size() != d.Size:
+			ids[i] = -1
+			stats.Updated++
+		default:
+			ids[i] = len(kept)
+			kept = append(kept, d)
+			delete(current, d.Path)
+		}
+	}
+	if len(kept) != len(idx.Docs) {
+		for term, postings := range idx.Terms {
+			filtered := postings[:0]
+			for _, p := range postings {
+				if id := ids[p.Doc]; id >= 0 {
+					p.Doc = id
+					filtered = append(filtered, p)
+				}
+			}
+			if len(filtered) == 0 {
+				delete(idx.Terms, term)
+			} else {
+				idx.Terms[term] = filtered
+			}
+		}
+		idx.Docs = kept
+	}
+
+	for rel, info := range current {
+		data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(rel)))
+		if err != nil {
+			return stats, err
+		}
+		idx.add(rel, info, string(data))
+	}
+	stats.Added = len(current) - stats.Updated
+	stats.Total = len(idx.Docs)
+	return stats, idx.Save(root)
+}
+
+func (idx *Index) add(rel string, info fs.FileInfo, content string) {
+	doc := Doc{
+		Path:    rel,
+		ModTime: info.ModTime().UnixNano(),
+		Size:    info.Size(),
+	}
+	var texts [numFields]string
+	doc.Course, doc.Title, texts[fieldBody], texts[fieldComments] = splitMarkdown(rel, content)
+	texts[fieldCourse] = doc.Course
+	texts[fieldTitle] = doc.Title
+
+	id := len(idx.Docs)
+	for f := field(0); f < numFields; f++ {
+		terms := tokenize(texts[f])
+		doc.Lengths[f] = len(terms)
+		positions := make(map[string][]int)
+		for pos, t := range terms {
+			positions[t] = append(positions[t], pos)
+		}
+		for t, ps := range positions {
+			idx.Terms[t] = append(idx.Terms[t], Posting{Doc: id, Field: f, Positions: ps})
+		}
+	}
+	idx.Docs = append(idx.Docs, doc)
+}
+
+// splitMarkdown split article markdown into course, title, body and comments. Course is the
+// first dir under markdown, title is the first level one heading or file name
+func splitMarkdown(rel, content string) (course, title, body, comments string) {
+	parts := strings.Split(rel, "/")
+	if len(parts) > 2 {
+		course = parts[1]
+	}
+	title = strings.TrimSuffix(parts[len(parts)-1], filepath.Ext(rel))
+	body = markdown.StripFrontMatter(content)
+	if strings.HasPrefix(body, "# ") {
+		line, rest, _ := strings.Cut(body, "\n")
+		title = strings.TrimSpace(strings.TrimPrefix(line, "# "))
+		body = rest
+	}
+	if i := strings.Index(body, commentsHeading); i >= 0 {
+		body, comments = body[:i], body[i+len(commentsHeading):]
+	}
+	return course, title, stripMarkdown(body), stripMarkdown(comments)
+}
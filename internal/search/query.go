@@ -0,0 +1,90 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// clause is one required part of query, terms must appear at consecutive positions of one of
+// its fields
+type clause struct {
+	fields []field
+	terms  []string
+	// text is used to locate snippet
+	text string
+}
+
+var fieldAliases = map[string]field{
+	"course":   fieldCourse,
+	"课程":       fieldCourse,
+	"title":    fieldTitle,
+	"标题":       fieldTitle,
+	"body":     fieldBody,
+	"正文":       fieldBody,
+	"comments": fieldComments,
+	"comment":  fieldComments,
+	"评论":       fieldComments,
+}
+
+var allFields = []field{fieldCourse, fieldTitle, fieldBody, fieldComments}
+
+// parseQuery parse query like `title:"分布式 事务" course:mysql 索引`. Clauses are separated by
+// space and all of them are required, quoted text is a phrase, a clause can be limited to one
+// field with field: prefix
+func parseQuery(q string) ([]clause, error) {
+	var clauses []clause
+	rest := strings.TrimSpace(q)
+	for rest != "" {
+		fields := allFields
+		if i := strings.IndexAny(rest, `: "`); i > 0 && rest[i] == ':' {
+			f, ok := fieldAliases[strings.ToLower(rest[:i])]
+			if !ok {
+				return nil, fmt.Errorf("不支持的搜索字段: %s", rest[:i])
+			}
+			fields = []field{f}
+			rest = rest[i+1:]
+		}
+
+		var text string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				return nil, fmt.Errorf("引号不匹配: %s", q)
+			}
+			text, rest = rest[1:end+1], rest[end+2:]
+		} else {
+			end := strings.IndexByte(rest, ' ')
+			if end < 0 {
+				end = len(rest)
+			}
+			text, rest = rest[:end], rest[end:]
+		}
+		rest = strings.TrimSpace(rest)
+
+		terms := tokenize(text)
+		if len(terms) == 0 {
+			continue
+		}
+		clauses = append(clauses, clause{fields: fields, terms: terms, text: strings.TrimSpace(text)})
+	}
+	if len(clauses) == 0 {
+		return nil, fmt.Errorf("搜索内容为空")
+	}
+	return clauses, nil
+}
+
+// expand return index terms matching single CJK character, which is only indexed inside bigrams
+func (idx *Index) expand(term string) []string {
+	r, size := utf8.DecodeRuneInString(term)
+	if size != len(term) || !isCJK(r) {
+		return []string{term}
+	}
+	expanded := []string{term}
+	for t := range idx.Terms {
+		if t != term && strings.ContainsRune(t, r) && utf8.RuneCountInString(t) == 2 {
+			expanded = append(expanded, t)
+		}
+	}
+	return expanded
+}
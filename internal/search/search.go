@@ -0,0 +1,233 @@
+package search
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	snippetBefore = 30
+	snippetAfter  = 70
+)
+
+// matches in title are more relevant than body and comments
+var fieldWeights = [numFields]float64{2, 3, 1, 0.5}
+
+// Hit is one search result
+type Hit struct {
+	// Path is absolute path of markdown file
+	Path    string
+	Course  string
+	Title   string
+	Score   float64
+	Snippet string
+}
+
+// Search find articles matching query in index of root, ranked by BM25 over fields
+func Search(root, query string, limit int) ([]Hit, error) {
+	clauses, err := parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := Load(root)
+	if err != nil {
+		return nil, err
+	}
+	return idx.search(root, clauses, limit), nil
+}
+
+func (idx *Index) search(root string, clauses []clause, limit int) []Hit {
+	if len(idx.Docs) == 0 {
+		return nil
+	}
+	var avgLengths [numFields]float64
+	for _, d := range idx.Docs {
+		for f, l := range d.Lengths {
+			avgLengths[f] += float64(l)
+		}
+	}
+	for f := range avgLengths {
+		avgLengths[f] = math.Max(avgLengths[f]/float64(len(idx.Docs)), 1)
+	}
+
+	scores := make(map[int]float64)
+	for i, c := range clauses {
+		// term frequency of clause per doc and field
+		tfs := idx.match(c)
+		if len(tfs) == 0 {
+			return nil
+		}
+		n := float64(len(idx.Docs))
+		df := float64(len(tfs))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		next := make(map[int]float64, len(tfs))
+		for doc, byField := range tfs {
+			prev, ok := scores[doc]
+			if i > 0 && !ok {
+				continue
+			}
+			score := prev
+			for f, tf := range byField {
+				if tf == 0 {
+					continue
+				}
+				norm := 1 - bm25B + bm25B*float64(idx.Docs[doc].Lengths[f])/avgLengths[f]
+				score += fieldWeights[f] * idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+			}
+			next[doc] = score
+		}
+		scores = next
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for doc, score := range scores {
+		d := idx.Docs[doc]
+		hits = append(hits, Hit{
+			Path:   filepath.Join(root, filepath.FromSlash(d.Path)),
+			Course: d.Course,
+			Title:  d.Title,
+			Score:  score,
+		})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Path < hits[j].Path
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	for i := range hits {
+		hits[i].Snippet = snippet(hits[i].Path, clauses)
+	}
+	return hits
+}
+
+// match return phrase frequency of clause in each field of matched docs
+func (idx *Index) match(c clause) map[int]*[numFields]float64 {
+	result := make(map[int]*[numFields]float64)
+	add := func(doc int, f field, n int) {
+		if result[doc] == nil {
+			result[doc] = &[numFields]float64{}
+		}
+		result[doc][f] += float64(n)
+	}
+	allowed := func(f field) bool {
+		for _, a := range c.fields {
+			if a == f {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(c.terms) == 1 {
+		for _, t := range idx.expand(c.terms[0]) {
+			for _, p := range idx.Terms[t] {
+				if allowed(p.Field) {
+					add(p.Doc, p.Field, len(p.Positions))
+				}
+			}
+		}
+		return result
+	}
+
+	// positions of every term keyed by doc and field
+	type key struct {
+		doc   int
+		field field
+	}
+	positions := make([]map[key][]int, len(c.terms))
+	for i, t := range c.terms {
+		positions[i] = make(map[key][]int)
+		for _, p := range idx.Terms[t] {
+			if allowed(p.Field) {
+				positions[i][key{p.Doc, p.Field}] = p.Positions
+			}
+		}
+	}
+	for k, first := range positions[0] {
+		n := 0
+		for _, start := range first {
+			ok := true
+			for i := 1; i < len(c.terms) && ok; i++ {
+				ok = containsInt(positions[i][k], start+i)
+			}
+			if ok {
+				n++
+			}
+		}
+		if n > 0 {
+			add(k.doc, k.field, n)
+		}
+	}
+	return result
+}
+
+// containsInt search sorted positions
+func containsInt(sorted []int, v int) bool {
+	i := sort.SearchInts(sorted, v)
+	return i < len(sorted) && sorted[i] == v
+}
+
+// snippet return text around first match of clauses in body or comments, matched text is
+// wrapped in 【】
+func snippet(fileName string, clauses []clause) string {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return ""
+	}
+	_, _, body, comments := splitMarkdown("", string(data))
+	for _, text := range []string{body, comments} {
+		runes := []rune(strings.Join(strings.Fields(text), " "))
+		lower := make([]rune, len(runes))
+		for i, r := range runes {
+			lower[i] = unicode.ToLower(r)
+		}
+		for _, c := range clauses {
+			needle := []rune(strings.ToLower(strings.Join(strings.Fields(c.text), " ")))
+			i := indexRunes(lower, needle)
+			if i < 0 {
+				continue
+			}
+			start := max(i-snippetBefore, 0)
+			end := min(i+len(needle)+snippetAfter, len(runes))
+			s := string(runes[start:i]) + "【" + string(runes[i:i+len(needle)]) + "】" + string(runes[i+len(needle):end])
+			if start > 0 {
+				s = "…" + s
+			}
+			if end < len(runes) {
+				s += "…"
+			}
+			return s
+		}
+	}
+	return ""
+}
+
+func indexRunes(s, sub []rune) int {
+	if len(sub) == 0 {
+		return -1
+	}
+	for i := 0; i+len(sub) <= len(s); i++ {
+		match := true
+		for j := range sub {
+			if s[i+j] != sub[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,101 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenize(t *testing.T) {
+	got := tokenize("Go 语言的 MySQL索引, 是")
+	want := []string{"go", "语言", "言的", "mysql", "索引", "是"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tokenize() = %v, want %v", got, want)
+	}
+}
+
+func writeArticle(t *testing.T, root, course, title, content string) string {
+	t.Helper()
+	dir := filepath.Join(root, markdownDir, course)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	fileName := filepath.Join(dir, title+".md")
+	if err := os.WriteFile(fileName, []byte("# "+title+"\n"+content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return fileName
+}
+
+func TestSearch(t *testing.T) {
+	root := t.TempDir()
+	writeArticle(t, root, "MySQL实战45讲", "04 深入浅出索引", "索引的出现是为了提高数据查询的效率。\n\n## 精选留言\n\n**张三** 讲得好")
+	writeArticle(t, root, "MySQL实战45讲", "08 事务到底是隔离的还是不隔离的", "事务隔离级别与索引无关")
+	other := writeArticle(t, root, "Go 语言核心36讲", "01 工作区和GOPATH", "Go 语言的源码文件")
+
+	stats, err := Update(root, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Added != 3 || stats.Total != 3 {
+		t.Fatalf("Update() = %+v", stats)
+	}
+
+	tests := []struct {
+		query  string
+		titles []string
+	}{
+		{"索引", []string{"04 深入浅出索引", "08 事务到底是隔离的还是不隔离的"}},
+		{"title:索引", []string{"04 深入浅出索引"}},
+		{`"提高数据查询"`, []string{"04 深入浅出索引"}},
+		{`"数据提高"`, nil},
+		{"course:go 源码", []string{"01 工作区和GOPATH"}},
+		{"comments:张三", []string{"04 深入浅出索引"}},
+		{"body:张三", nil},
+	}
+	for _, tt := range tests {
+		hits, err := Search(root, tt.query, 10)
+		if err != nil {
+			t.Fatalf("Search(%q) error: %v", tt.query, err)
+		}
+		var titles []string
+		for _, h := range hits {
+			titles = append(titles, h.Title)
+		}
+		if !reflect.DeepEqual(titles, tt.titles) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, titles, tt.titles)
+		}
+	}
+
+	hits, _ := Search(root, "查询", 10)
+	if len(hits) != 1 || !strings.Contains(hits[0].Snippet, "【查询】") {
+		t.Errorf("unexpected snippet: %+v", hits)
+	}
+
+	// incremental update only touches changed files
+	future := time.Now().Add(time.Hour)
+	if err := os.WriteFile(other, []byte("# 01 工作区和GOPATH\n模块代理"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_ = os.Chtimes(other, future, future)
+	_ = os.Remove(filepath.Join(root, markdownDir, "MySQL实战45讲", "08 事务到底是隔离的还是不隔离的.md"))
+	stats, err = Update(root, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Added != 0 || stats.Updated != 1 || stats.Removed != 1 || stats.Total != 2 {
+		t.Fatalf("Update() = %+v", stats)
+	}
+	if hits, _ := Search(root, "源码", 10); len(hits) != 0 {
+		t.Errorf("stale postings found: %+v", hits)
+	}
+	if hits, _ := Search(root, "模块", 10); len(hits) != 1 {
+		t.Errorf("updated doc not found: %+v", hits)
+	}
+	if hits, _ := Search(root, "索引", 10); len(hits) != 1 || hits[0].Title != "04 深入浅出索引" {
+		t.Errorf("renumbered doc not found: %+v", hits)
+	}
+}
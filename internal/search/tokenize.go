@@ -0,0 +1,62 @@
+package search
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	mdImageRegexp = regexp.MustCompile(`!\[[^\]]*]\([^)]*\)`)
+	mdLinkRegexp  = regexp.MustCompile(`]\([^)]*\)`)
+)
+
+// stripMarkdown remove images and link targets which are noise for search
+func stripMarkdown(s string) string {
+	s = mdImageRegexp.ReplaceAllString(s, "")
+	return mdLinkRegexp.ReplaceAllString(s, "]")
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// tokenize split text into terms, index of term is its position. Latin words and numbers are
+// lowercased terms, runs of CJK characters are split into overlapping bigrams, a single CJK
+// character between other characters is kept as is
+func tokenize(s string) []string {
+	var terms []string
+	var word []rune
+	var cjk []rune
+	flushWord := func() {
+		if len(word) > 0 {
+			terms = append(terms, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		if len(cjk) == 1 {
+			terms = append(terms, string(cjk))
+		}
+		for i := 0; i+1 < len(cjk); i++ {
+			terms = append(terms, string(cjk[i:i+2]))
+		}
+		cjk = cjk[:0]
+	}
+	for _, r := range s {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return terms
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nicoxiang/geektime-downloader/internal/links"
+)
+
+// output kind dirs under download folder
+const (
+	kindPDF      = "pdf"
+	kindMarkdown = "markdown"
+	kindAudio    = "audio"
+	kindEPUB     = "epub"
+	kindHTML     = "html"
+)
+
+var kinds = []string{kindPDF, kindMarkdown, kindAudio, kindEPUB, kindHTML}
+
+// courseSummary is one course of library index
+type courseSummary struct {
+	Name  string
+	Kinds []string
+}
+
+// fileGroup is files of one kind in course page, paths are relative to download folder
+type fileGroup struct {
+	Title string
+	Files []string
+}
+
+// listCourses return courses in any kind dir of root, sorted by name
+func listCourses(root string) []courseSummary {
+	m := make(map[string]*courseSummary)
+	for _, kind := range kinds {
+		entries, err := os.ReadDir(filepath.Join(root, kind))
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+				continue
+			}
+			c, ok := m[e.Name()]
+			if !ok {
+				c = &courseSummary{Name: e.Name()}
+				m[e.Name()] = c
+			}
+			c.Kinds = append(c.Kinds, kind)
+		}
+	}
+	courses := make([]courseSummary, 0, len(m))
+	for _, c := range m {
+		courses = append(courses, *c)
+	}
+	sort.Slice(courses, func(i, j int) bool {
+		return courses[i].Name < courses[j].Name
+	})
+	return courses
+}
+
+// courseFiles group downloaded files of course by kind, course level outputs like merged pdf,
+// audiobook and epub are saved next to course dir. Article files are in course order of manifest.
+func courseFiles(root, course string) []fileGroup {
+	order := links.CourseOrder(root, course)
+	groups := []fileGroup{
+		{Title: "文章", Files: walkFiles(root, kindMarkdown, course, order, ".md")},
+		{Title: "PDF", Files: append(siblingFiles(root, kindPDF, course, ".pdf"), walkFiles(root, kindPDF, course, order, ".pdf")...)},
+		{Title: "音频", Files: append(siblingFiles(root, kindAudio, course, ".m4b"), walkFiles(root, kindAudio, course, order, ".mp3")...)},
+		{Title: "视频", Files: walkFiles(root, kindPDF, course, order, ".ts", ".mp4")},
+		{Title: "电子书", Files: siblingFiles(root, kindEPUB, course, ".epub")},
+	}
+	index := filepath.Join(kindHTML, course, "index.html")
+	if _, err := os.Stat(filepath.Join(root, index)); err == nil {
+		groups = append(groups, fileGroup{Title: "网页", Files: []string{filepath.ToSlash(index)}})
+	}
+
+	var result []fileGroup
+	for _, g := range groups {
+		if len(g.Files) > 0 {
+			result = append(result, g)
+		}
+	}
+	return result
+}
+
+// walkFiles find files with exts in root/kind/course, images and comments are skipped. Files of
+// articles in order come first in course order, others follow by name
+func walkFiles(root, kind, course string, order map[string]int, exts ...string) []string {
+	var result []string
+	base := filepath.Join(root, kind, course)
+	_ = filepath.WalkDir(base, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if p != base && (d.Name() == "images" || d.Name() == "comments" || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if hasExt(d.Name(), exts) {
+			rel, _ := filepath.Rel(root, p)
+			result = append(result, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	sort.Strings(result)
+	prefix := filepath.ToSlash(filepath.Join(kind, course)) + "/"
+	sort.SliceStable(result, func(i, j int) bool {
+		oi := articleIndex(strings.TrimPrefix(result[i], prefix), order)
+		oj := articleIndex(strings.TrimPrefix(result[j], prefix), order)
+		if oi == 0 || oj == 0 {
+			return oi != 0 && oj == 0
+		}
+		return oi < oj
+	})
+	return result
+}
+
+// articleIndex return position in course of file relative to course dir, zero if unknown. Besides
+// <name>.<ext>, markdown flavors save articles as <name>/index.md or docs/<name>.md and videos
+// embedded in text articles are saved as videos/<name>/*.mp4
+func articleIndex(rel string, order map[string]int) int {
+	stem := strings.TrimSuffix(rel, path.Ext(rel))
+	keys := []string{stem}
+	if s, ok := strings.CutSuffix(stem, "/index"); ok {
+		keys = append(keys, s)
+	}
+	if s, ok := strings.CutPrefix(stem, "docs/"); ok {
+		keys = append(keys, s)
+	}
+	if s, ok := strings.CutPrefix(rel, "videos/"); ok {
+		keys = append(keys, path.Dir(s))
+	}
+	for _, k := range keys {
+		if i, ok := order[k]; ok {
+			return i
+		}
+	}
+	return 0
+}
+
+// siblingFiles find root/kind/course.ext
+func siblingFiles(root, kind, course string, exts ...string) []string {
+	var result []string
+	for _, ext := range exts {
+		rel := filepath.Join(kind, course+ext)
+		if _, err := os.Stat(filepath.Join(root, rel)); err == nil {
+			result = append(result, filepath.ToSlash(rel))
+		}
+	}
+	return result
+}
+
+func hasExt(name string, exts []string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range exts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/nicoxiang/geektime-downloader/internal/geektime"
+	"github.com/nicoxiang/geektime-downloader/internal/links"
+)
+
+func TestCourseFilesOrder(t *testing.T) {
+	root := t.TempDir()
+	course := geektime.Course{Title: "course", Articles: []geektime.Article{
+		{AID: 1, Title: "开篇词"},
+		{AID: 2, Title: "b", Name: filepath.Join("基础篇", "b")},
+		{AID: 3, Title: "a", Name: filepath.Join("基础篇", "a")},
+	}}
+	if err := links.SaveManifest(root, course); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{
+		"markdown/course/基础篇/a.md",
+		"markdown/course/开篇词.md",
+		"markdown/course/extra.md",
+		"markdown/course/基础篇/b.md",
+		"markdown/course/images/x.md",
+		"pdf/course/videos/基础篇/b/1.mp4",
+		"pdf/course/开篇词.ts",
+	} {
+		fileName := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(fileName), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fileName, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	order := links.CourseOrder(root, "course")
+	want := []string{
+		"markdown/course/开篇词.md",
+		"markdown/course/基础篇/b.md",
+		"markdown/course/基础篇/a.md",
+		"markdown/course/extra.md",
+	}
+	if got := walkFiles(root, kindMarkdown, "course", order, ".md"); !reflect.DeepEqual(got, want) {
+		t.Errorf("markdown = %q, want %q", got, want)
+	}
+	want = []string{"pdf/course/开篇词.ts", "pdf/course/videos/基础篇/b/1.mp4"}
+	if got := walkFiles(root, kindPDF, "course", order, ".ts", ".mp4"); !reflect.DeepEqual(got, want) {
+		t.Errorf("videos = %q, want %q", got, want)
+	}
+	if got := articleIndex("docs/基础篇/a.md", order); got != 3 {
+		t.Errorf("index of mkdocs article = %d, want 3", got)
+	}
+	if got := articleIndex("基础篇/a/index.md", order); got != 3 {
+		t.Errorf("index of hugo article = %d, want 3", got)
+	}
+}
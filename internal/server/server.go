@@ -0,0 +1,255 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nicoxiang/geektime-downloader/internal/markdown"
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/logger"
+	"github.com/nicoxiang/geektime-downloader/internal/search"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+const (
+	filesPrefix = "/files/"
+	searchLimit = 50
+)
+
+// content types not in (or inconsistent across) system mime tables
+var contentTypes = map[string]string{
+	".md":   "text/markdown; charset=utf-8",
+	".mp3":  "audio/mpeg",
+	".m4b":  "audio/mp4",
+	".ts":   "video/mp2t",
+	".mp4":  "video/mp4",
+	".pdf":  "application/pdf",
+	".epub": "application/epub+zip",
+}
+
+var markdownRenderer = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+type fileLink struct {
+	Name string
+	Href template.URL
+}
+
+type server struct {
+	root  string
+	files http.Handler
+}
+
+// Serve serve downloaded library in root until ctx is done. Courses are listed from pdf, markdown,
+// audio, epub and html dirs, markdown is rendered as html, other files are served with range support
+func Serve(ctx context.Context, root, addr string) error {
+	if _, err := os.Stat(root); err != nil {
+		return err
+	}
+	s := &server{root: root, files: http.StripPrefix(filesPrefix, http.FileServer(http.Dir(root)))}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handleLibrary)
+	mux.HandleFunc("GET /course/{name}", s.handleCourse)
+	mux.HandleFunc("GET /search", s.handleSearch)
+	mux.HandleFunc("GET "+filesPrefix, s.handleFile)
+
+	srv := &http.Server{
+		Addr: addr,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger.Infof("Library request, method: %s, url: %s, remote: %s", r.Method, r.URL, r.RemoteAddr)
+			mux.ServeHTTP(w, r)
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *server) handleLibrary(w http.ResponseWriter, r *http.Request) {
+	type course struct {
+		courseSummary
+		Href template.URL
+	}
+	var courses []course
+	for _, c := range listCourses(s.root) {
+		courses = append(courses, course{c, template.URL("/course/" + url.PathEscape(c.Name))})
+	}
+	render(w, libraryTemplate, struct {
+		Style   template.CSS
+		Courses []course
+	}{template.CSS(styleCSS), courses})
+}
+
+func (s *server) handleCourse(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		http.NotFound(w, r)
+		return
+	}
+	type group struct {
+		Title string
+		Links []fileLink
+	}
+	var groups []group
+	for _, g := range courseFiles(s.root, name) {
+		gr := group{Title: g.Title}
+		for _, f := range g.Files {
+			gr.Links = append(gr.Links, fileLink{Name: displayName(f, name), Href: fileURL(f)})
+		}
+		groups = append(groups, gr)
+	}
+	if len(groups) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	render(w, courseTemplate, struct {
+		Style  template.CSS
+		Title  string
+		Groups []group
+	}{template.CSS(styleCSS), name, groups})
+}
+
+// handleSearch search index built by index command, results link to rendered markdown
+func (s *server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	type result struct {
+		search.Hit
+		Href template.URL
+	}
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	var results []result
+	var message string
+	switch {
+	case query == "":
+	case !search.Exists(s.root):
+		message = "搜索索引不存在, 请先执行 index 命令建立索引"
+	default:
+		hits, err := search.Search(s.root, query, searchLimit)
+		if err != nil {
+			message = err.Error()
+			break
+		}
+		for _, h := range hits {
+			rel, err := filepath.Rel(s.root, h.Path)
+			if err != nil {
+				continue
+			}
+			results = append(results, result{h, fileURL(filepath.ToSlash(rel))})
+		}
+		if len(results) == 0 {
+			message = "没有找到匹配的文章"
+		}
+	}
+	render(w, searchTemplate, struct {
+		Style   template.CSS
+		Query   string
+		Message string
+		Results []result
+	}{template.CSS(styleCSS), query, message, results})
+}
+
+// handleFile render markdown as html unless raw query is set, other files are served by
+// http.FileServer which supports range requests used by audio and video players
+func (s *server) handleFile(w http.ResponseWriter, r *http.Request) {
+	rel := strings.TrimPrefix(r.URL.Path, filesPrefix)
+	ext := strings.ToLower(path.Ext(rel))
+	if ext == ".md" && !r.URL.Query().Has("raw") {
+		s.renderMarkdown(w, r, rel)
+		return
+	}
+	if ct, ok := contentTypes[ext]; ok {
+		w.Header().Set("Content-Type", ct)
+	}
+	if ext == ".pdf" {
+		w.Header().Set("Content-Disposition", "inline")
+	}
+	s.files.ServeHTTP(w, r)
+}
+
+func (s *server) renderMarkdown(w http.ResponseWriter, r *http.Request, rel string) {
+	f, err := http.Dir(s.root).Open(rel)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	var src bytes.Buffer
+	if _, err := src.ReadFrom(f); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var content bytes.Buffer
+	if err := markdownRenderer.Convert([]byte(markdown.StripFrontMatter(src.String())), &content); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// markdown/<course>/...
+	var course template.URL
+	if parts := strings.SplitN(rel, "/", 3); len(parts) == 3 {
+		course = template.URL("/course/" + url.PathEscape(parts[1]))
+	}
+	render(w, markdownTemplate, struct {
+		Style   template.CSS
+		Title   string
+		Course  template.URL
+		Raw     template.URL
+		Content template.HTML
+	}{
+		Style:   template.CSS(styleCSS),
+		Title:   strings.TrimSuffix(path.Base(rel), path.Ext(rel)),
+		Course:  course,
+		Raw:     fileURL(rel) + "?raw",
+		Content: template.HTML(content.String()),
+	})
+}
+
+func render(w http.ResponseWriter, t *template.Template, data any) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(buf.Bytes())
+}
+
+// fileURL escape every segment of slash separated path relative to root
+func fileURL(rel string) template.URL {
+	return template.URL(filesPrefix + (&url.URL{Path: rel}).EscapedPath())
+}
+
+// displayName strip kind/course prefix and extension, course level files keep their name
+func displayName(rel, course string) string {
+	parts := strings.SplitN(rel, "/", 3)
+	name := path.Base(rel)
+	if len(parts) == 3 && parts[1] == course {
+		name = parts[2]
+	}
+	switch path.Base(rel) {
+	case "index.html":
+		return "离线网页"
+	case "index.md":
+		// hugo page bundle
+		return path.Dir(name)
+	}
+	return strings.TrimSuffix(name, path.Ext(name))
+}
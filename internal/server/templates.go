@@ -0,0 +1,92 @@
+package server
+
+import "html/template"
+
+const styleCSS = `
+body { max-width: 900px; margin: 0 auto; padding: 1em 1.5em; font-family: -apple-system, "PingFang SC", "Microsoft YaHei", sans-serif; line-height: 1.75; color: #24292e; }
+a { color: #0366d6; text-decoration: none; }
+a:hover { text-decoration: underline; }
+img { max-width: 100%; height: auto; }
+pre { background: #f6f8fa; padding: 1em; overflow: auto; font-size: 0.85em; line-height: 1.45; }
+code { font-family: SFMono-Regular, Consolas, Menlo, monospace; }
+blockquote { margin: 1em 0; padding: 0 1em; color: #6a737d; border-left: 4px solid #dfe2e5; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #dfe2e5; padding: 0.4em 0.8em; }
+nav { font-size: 0.9em; color: #6a737d; }
+.snippet { margin: 0; }
+.meta { color: #6a737d; font-size: 0.9em; }
+`
+
+var libraryTemplate = template.Must(template.New("library").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>课程列表</title>
+<style>{{.Style}}</style>
+</head>
+<body>
+<h1>课程列表</h1>
+<form action="/search"><input type="search" name="q" placeholder="搜索文章, 如 title:索引 &quot;分布式事务&quot;" size="40"> <button type="submit">搜索</button></form>
+{{if .Courses}}<ul>
+{{range .Courses}}<li><a href="{{.Href}}">{{.Name}}</a> <span class="meta">{{range $i, $k := .Kinds}}{{if $i}} · {{end}}{{$k}}{{end}}</span></li>
+{{end}}</ul>{{else}}<p>下载目录中还没有课程</p>{{end}}
+</body>
+</html>
+`))
+
+var courseTemplate = template.Must(template.New("course").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>{{.Style}}</style>
+</head>
+<body>
+<nav><a href="/">课程列表</a></nav>
+<h1>{{.Title}}</h1>
+{{range .Groups}}<h2>{{.Title}}</h2>
+<ol>
+{{range .Links}}<li><a href="{{.Href}}">{{.Name}}</a></li>
+{{end}}</ol>
+{{end}}</body>
+</html>
+`))
+
+var markdownTemplate = template.Must(template.New("markdown").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>{{.Style}}</style>
+</head>
+<body>
+<nav><a href="/">课程列表</a>{{if .Course}} / <a href="{{.Course}}">返回课程</a>{{end}} · <a href="{{.Raw}}">原始 Markdown</a></nav>
+<article>
+{{.Content}}
+</article>
+</body>
+</html>
+`))
+
+var searchTemplate = template.Must(template.New("search").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{if .Query}}{{.Query}} - {{end}}搜索</title>
+<style>{{.Style}}</style>
+</head>
+<body>
+<nav><a href="/">课程列表</a></nav>
+<h1>搜索</h1>
+<form action="/search"><input type="search" name="q" value="{{.Query}}" placeholder="搜索文章, 如 title:索引 &quot;分布式事务&quot;" size="40"> <button type="submit">搜索</button></form>
+{{if .Message}}<p class="meta">{{.Message}}</p>{{end}}
+{{if .Results}}<ol>
+{{range .Results}}<li><a href="{{.Href}}">{{.Title}}</a> <span class="meta">{{.Course}}</span>{{if .Snippet}}<p class="snippet meta">{{.Snippet}}</p>{{end}}</li>
+{{end}}</ol>{{end}}
+</body>
+</html>
+`))
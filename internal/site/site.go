@@ -0,0 +1,240 @@
+package site
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"html/template"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/nicoxiang/geektime-downloader/internal/geektime"
+	"github.com/nicoxiang/geektime-downloader/internal/links"
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/files"
+	"github.com/nicoxiang/geektime-downloader/internal/pkg/htmlutil"
+	nethtml "golang.org/x/net/html"
+)
+
+const (
+	// HTMLExtension ...
+	HTMLExtension = ".html"
+	// IndexFileName is index page of course and library
+	IndexFileName = "index.html"
+	// CourseFileName is course metadata used to build library index
+	CourseFileName = "course.json"
+)
+
+// link to article or course page, href is escaped so that it works with file://
+type link struct {
+	Title  string
+	Href   template.URL
+	Exists bool
+}
+
+type section struct {
+	Title string
+	Links []link
+}
+
+// courseMeta is saved as course.json in course dir
+type courseMeta struct {
+	ID       int                `json:"id"`
+	Title    string             `json:"title"`
+	Author   string             `json:"author"`
+	Cover    string             `json:"cover"`
+	Articles []geektime.Article `json:"articles"`
+}
+
+// ArticleFileName return html file name of article, named by ID so that articles of same title
+// do not overwrite each other
+func ArticleFileName(aid int) string {
+	return "article-" + strconv.Itoa(aid) + HTMLExtension
+}
+
+func hrefOf(fileName string) template.URL {
+	return template.URL(url.PathEscape(fileName))
+}
+
+// SaveArticle save article as standalone html page with localized images, previous/next links
+// follow article order of course. Links to articles of this course and downloaded articles of
+// other courses are rewritten to relative links.
+func SaveArticle(ctx context.Context,
+	dir string,
+	course geektime.Course,
+	aid int,
+	title,
+	articleHTML string,
+	comments []geektime.Comment,
+	resolver *links.Resolver,
+	overwrite bool,
+) (bool, error) {
+	fullName := filepath.Join(dir, ArticleFileName(aid))
+	if files.CheckFileExists(fullName) && !overwrite {
+		return true, nil
+	}
+
+	body, err := htmlutil.ParseBody(articleHTML)
+	if err != nil {
+		return false, err
+	}
+	htmlutil.RemoveElements(body, "script")
+	htmlutil.AbsolutizeLinks(body)
+	htmlutil.RewriteLinks(body, func(href string) (string, bool) {
+		target, sameCourse, ok := resolver.Resolve(title, href)
+		targetID, _ := links.ArticleID(href)
+		fileName := ArticleFileName(targetID)
+		switch {
+		case sameCourse:
+			return string(hrefOf(fileName)), true
+		case ok && files.CheckFileExists(filepath.Join(filepath.Dir(dir), target.Course, fileName)):
+			return "../" + url.PathEscape(target.Course) + "/" + string(hrefOf(fileName)), true
+		}
+		return "", false
+	})
+	if err := htmlutil.LocalizeImages(ctx, body, dir); err != nil {
+		return false, err
+	}
+	var content bytes.Buffer
+	for c := body.FirstChild; c != nil; c = c.NextSibling {
+		if err := nethtml.Render(&content, c); err != nil {
+			return false, err
+		}
+	}
+
+	data := struct {
+		Style        template.CSS
+		Course       geektime.Course
+		Title        string
+		SectionTitle string
+		Content      template.HTML
+		Comments     template.HTML
+		Prev, Next   *link
+	}{
+		Style:   template.CSS(styleCSS),
+		Course:  course,
+		Title:   title,
+		Content: template.HTML(content.String()),
+	}
+	if len(comments) > 0 {
+		data.Comments = template.HTML(htmlutil.CommentsHTML(comments))
+	}
+	for i, a := range course.Articles {
+		if a.AID != aid {
+			continue
+		}
+		data.SectionTitle = a.SectionTitle
+		if i > 0 {
+			p := course.Articles[i-1]
+			data.Prev = &link{Title: p.Title, Href: hrefOf(ArticleFileName(p.AID))}
+		}
+		if i < len(course.Articles)-1 {
+			n := course.Articles[i+1]
+			data.Next = &link{Title: n.Title, Href: hrefOf(ArticleFileName(n.AID))}
+		}
+		break
+	}
+
+	var buf bytes.Buffer
+	if err := articleTemplate.Execute(&buf, data); err != nil {
+		return false, err
+	}
+	return false, os.WriteFile(fullName, buf.Bytes(), 0644)
+}
+
+// WriteCourseIndex write index.html of course grouped by section and course.json, articles not
+// downloaded yet are listed without link
+func WriteCourseIndex(course geektime.Course, dir string) error {
+	var sections []*section
+	for _, a := range course.Articles {
+		if len(sections) == 0 || sections[len(sections)-1].Title != a.SectionTitle {
+			sections = append(sections, &section{Title: a.SectionTitle})
+		}
+		fileName := ArticleFileName(a.AID)
+		s := sections[len(sections)-1]
+		s.Links = append(s.Links, link{
+			Title:  a.Title,
+			Href:   hrefOf(fileName),
+			Exists: files.CheckFileExists(filepath.Join(dir, fileName)),
+		})
+	}
+
+	data := struct {
+		Style    template.CSS
+		Title    string
+		Author   string
+		Sections []*section
+	}{
+		Style:    template.CSS(styleCSS),
+		Title:    course.Title,
+		Author:   course.Author,
+		Sections: sections,
+	}
+	var buf bytes.Buffer
+	if err := courseTemplate.Execute(&buf, data); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, IndexFileName), buf.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	meta, err := json.MarshalIndent(courseMeta{
+		ID:       course.ID,
+		Title:    course.Title,
+		Author:   course.Author,
+		Cover:    course.Cover,
+		Articles: course.Articles,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, CourseFileName), meta, 0644)
+}
+
+// WriteLibraryIndex write index.html in root listing all courses which have course.json
+func WriteLibraryIndex(root string) error {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return err
+	}
+	type courseLink struct {
+		Title  string
+		Author string
+		Href   template.URL
+		Count  int
+	}
+	var courses []courseLink
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(root, e.Name(), CourseFileName))
+		if err != nil {
+			continue
+		}
+		var meta courseMeta
+		if err := json.Unmarshal(data, &meta); err != nil {
+			continue
+		}
+		courses = append(courses, courseLink{
+			Title:  meta.Title,
+			Author: meta.Author,
+			Href:   hrefOf(e.Name()) + "/" + IndexFileName,
+			Count:  len(meta.Articles),
+		})
+	}
+	sort.Slice(courses, func(i, j int) bool {
+		return courses[i].Title < courses[j].Title
+	})
+
+	var buf bytes.Buffer
+	if err := libraryTemplate.Execute(&buf, struct {
+		Style   template.CSS
+		Courses []courseLink
+	}{template.CSS(styleCSS), courses}); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(root, IndexFileName), buf.Bytes(), 0644)
+}
@@ -0,0 +1,84 @@
+package site
+
+import "html/template"
+
+const styleCSS = `
+body { max-width: 860px; margin: 0 auto; padding: 1em 1.5em; font-family: -apple-system, "PingFang SC", "Microsoft YaHei", sans-serif; line-height: 1.75; color: #24292e; }
+a { color: #0366d6; text-decoration: none; }
+a:hover { text-decoration: underline; }
+img { max-width: 100%; height: auto; }
+pre { background: #f6f8fa; padding: 1em; overflow: auto; font-size: 0.85em; line-height: 1.45; }
+code { font-family: SFMono-Regular, Consolas, Menlo, monospace; }
+blockquote { margin: 1em 0; padding: 0 1em; color: #6a737d; border-left: 4px solid #dfe2e5; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #dfe2e5; padding: 0.4em 0.8em; }
+nav.pager { display: flex; justify-content: space-between; gap: 1em; margin: 2em 0; padding-top: 1em; border-top: 1px solid #eaecef; }
+nav.breadcrumb { font-size: 0.9em; color: #6a737d; }
+.comment { margin-bottom: 1.2em; }
+.comment-meta { color: #6a737d; font-size: 0.9em; margin-bottom: 0; }
+.missing { color: #959da5; }
+.meta { color: #6a737d; }
+`
+
+var articleTemplate = template.Must(template.New("article").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}} - {{.Course.Title}}</title>
+<style>{{.Style}}</style>
+</head>
+<body>
+<nav class="breadcrumb"><a href="../index.html">课程列表</a> / <a href="index.html">{{.Course.Title}}</a>{{if .SectionTitle}} / {{.SectionTitle}}{{end}}</nav>
+<article>
+<h1>{{.Title}}</h1>
+{{.Content}}
+</article>
+{{if .Comments}}<section class="comments">
+<h2>精选留言</h2>
+{{.Comments}}</section>
+{{end}}<nav class="pager">
+<span>{{if .Prev}}<a href="{{.Prev.Href}}" rel="prev">← {{.Prev.Title}}</a>{{end}}</span>
+<a href="index.html">目录</a>
+<span>{{if .Next}}<a href="{{.Next.Href}}" rel="next">{{.Next.Title}} →</a>{{end}}</span>
+</nav>
+</body>
+</html>
+`))
+
+var courseTemplate = template.Must(template.New("course").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>{{.Style}}</style>
+</head>
+<body>
+<nav class="breadcrumb"><a href="../index.html">课程列表</a></nav>
+<h1>{{.Title}}</h1>
+{{if .Author}}<p class="meta">{{.Author}}</p>{{end}}
+{{range .Sections}}{{if .Title}}<h2>{{.Title}}</h2>{{end}}
+<ol>
+{{range .Links}}<li>{{if .Exists}}<a href="{{.Href}}">{{.Title}}</a>{{else}}<span class="missing">{{.Title}}</span>{{end}}</li>
+{{end}}</ol>
+{{end}}</body>
+</html>
+`))
+
+var libraryTemplate = template.Must(template.New("library").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>课程列表</title>
+<style>{{.Style}}</style>
+</head>
+<body>
+<h1>课程列表</h1>
+<ul>
+{{range .Courses}}<li><a href="{{.Href}}">{{.Title}}</a>{{if .Author}} <span class="meta">{{.Author}}</span>{{end}} <span class="meta">{{.Count}} 篇</span></li>
+{{end}}</ul>
+</body>
+</html>
+`))
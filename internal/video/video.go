@@ -46,13 +46,15 @@ type GetPlayInfoResponse struct {
 	PlayInfoList vod.PlayInfoListInGetPlayInfo `json:"PlayInfoList" xml:"PlayInfoList"`
 }
 
-// DownloadArticleVideo download normal video cource ...
+// DownloadArticleVideo download normal video cource as name in projectDir, file name of video
+// title is used if name is empty
 // sourceType: normal video cource 1
 func DownloadArticleVideo(ctx context.Context,
 	client *geektime.Client,
 	articleID int,
 	sourceType int,
 	projectDir string,
+	name string,
 	quality string,
 	concurrency int,
 ) error {
@@ -71,18 +73,20 @@ func DownloadArticleVideo(ctx context.Context,
 	return downloadAliyunVodEncryptVideo(ctx,
 		client,
 		playAuth,
-		articleInfo.Data.Info.Title,
+		videoName(name, articleInfo.Data.Info.Title),
 		projectDir,
 		quality,
 		articleInfo.Data.Info.Video.ID,
 		concurrency)
 }
 
-// DownloadEnterpriseArticleVideo download enterprise video
+// DownloadEnterpriseArticleVideo download enterprise video as name in projectDir, file name of
+// video title is used if name is empty
 func DownloadEnterpriseArticleVideo(ctx context.Context,
 	client *geektime.Client,
 	articleID int,
 	projectDir string,
+	name string,
 	quality string,
 	concurrency int,
 ) error {
@@ -100,19 +104,21 @@ func DownloadEnterpriseArticleVideo(ctx context.Context,
 	return downloadAliyunVodEncryptVideo(ctx,
 		client,
 		playAuth,
-		articleInfo.Data.Article.Title,
+		videoName(name, articleInfo.Data.Article.Title),
 		projectDir,
 		quality,
 		articleInfo.Data.Video.ID,
 		concurrency)
 }
 
-// DownloadUniversityVideo ...
+// DownloadUniversityVideo download university video as name in projectDir, file name of video
+// title is used if name is empty
 func DownloadUniversityVideo(ctx context.Context,
 	client *geektime.Client,
 	articleID int,
 	currentProduct geektime.Course,
 	projectDir string,
+	name string,
 	quality string,
 	concurrency int) error {
 	playAuthInfo, err := client.UniversityVideoPlayAuth(articleID, currentProduct.ID)
@@ -124,17 +130,25 @@ func DownloadUniversityVideo(ctx context.Context,
 	return downloadAliyunVodEncryptVideo(ctx,
 		client,
 		playAuthInfo.Data.PlayAuth,
-		videoTitle,
+		videoName(name, videoTitle),
 		projectDir,
 		quality,
 		playAuthInfo.Data.VID,
 		concurrency)
 }
 
+// videoName return name of video file, file name of title is used if name is empty
+func videoName(name, title string) string {
+	if name != "" {
+		return name
+	}
+	return filenamify.Filenamify(title)
+}
+
 func downloadAliyunVodEncryptVideo(ctx context.Context,
 	client *geektime.Client,
 	playAuth,
-	videoTitle,
+	name,
 	projectDir,
 	quality,
 	videoID string,
@@ -160,13 +174,12 @@ func downloadAliyunVodEncryptVideo(ctx context.Context,
 	if isVodEncryptVideo {
 		decryptKey = crypto.GetAESDecryptKey(clientRand, playInfo.Rand, playInfo.Plaintext)
 	}
-	return download(ctx, tsURLPrefix, videoTitle, projectDir, tsFileNames, []byte(decryptKey), playInfo.Size, isVodEncryptVideo, concurrency)
+	return download(ctx, tsURLPrefix, name, projectDir, tsFileNames, []byte(decryptKey), playInfo.Size, isVodEncryptVideo, concurrency)
 }
 
-// DownloadMP4 download MP4 resources in article
-func DownloadMP4(ctx context.Context, title, projectDir string, mp4URLs []string, overwrite bool) (err error) {
-	filenamifyTitle := filenamify.Filenamify(title)
-	videoDir := filepath.Join(projectDir, "videos", filenamifyTitle)
+// DownloadMP4 download MP4 resources in article named name
+func DownloadMP4(ctx context.Context, name, projectDir string, mp4URLs []string, overwrite bool) (err error) {
+	videoDir := MP4Dir(projectDir, name)
 	if err = os.MkdirAll(videoDir, os.ModePerm); err != nil {
 		return
 	}
@@ -192,9 +205,15 @@ func DownloadMP4(ctx context.Context, title, projectDir string, mp4URLs []string
 	return
 }
 
+// MP4Dir return dir of mp4 videos embedded in article, name is path of article files relative to
+// course dir without extension
+func MP4Dir(projectDir, name string) string {
+	return filepath.Join(projectDir, "videos", name)
+}
+
 func download(ctx context.Context,
 	tsURLPrefix,
-	title,
+	name,
 	projectDir string,
 	tsFileNames []string,
 	decryptKey []byte,
@@ -203,8 +222,7 @@ func download(ctx context.Context,
 	concurrency int) (err error) {
 
 	// Make temp ts folder and download temp ts files
-	filenamifyTitle := filenamify.Filenamify(title)
-	tempVideoDir := filepath.Join(projectDir, filenamifyTitle)
+	tempVideoDir := filepath.Join(projectDir, name)
 	if err = os.MkdirAll(tempVideoDir, os.ModePerm); err != nil {
 		return
 	}
@@ -213,7 +231,7 @@ func download(ctx context.Context,
 		_ = os.RemoveAll(tempVideoDir)
 	}()
 
-	bar := newBar(size, fmt.Sprintf("[正在下载 %s] ", filenamifyTitle))
+	bar := newBar(size, fmt.Sprintf("[正在下载 %s] ", filepath.Base(name)))
 	bar.Start()
 
 	for _, tsFileName := range tsFileNames {
@@ -235,17 +253,17 @@ func download(ctx context.Context,
 	bar.Finish()
 
 	// Read temp ts files, decrypt and merge into the one final video file
-	err = mergeTSFiles(tempVideoDir, filenamifyTitle, projectDir, decryptKey, isVodEncryptVideo)
+	err = mergeTSFiles(tempVideoDir, name, projectDir, decryptKey, isVodEncryptVideo)
 
 	return
 }
 
-func mergeTSFiles(tempVideoDir, filenamifyTitle, projectDir string, key []byte, isVodEncryptVideo bool) error {
+func mergeTSFiles(tempVideoDir, name, projectDir string, key []byte, isVodEncryptVideo bool) error {
 	tempTSFiles, err := os.ReadDir(tempVideoDir)
 	if err != nil {
 		return err
 	}
-	fullPath := filepath.Join(projectDir, filenamifyTitle+TSExtension)
+	fullPath := filepath.Join(projectDir, name+TSExtension)
 	finalVideoFile, err := os.OpenFile(fullPath, os.O_APPEND|os.O_WRONLY|os.O_CREATE, os.ModePerm)
 	defer func() {
 		_ = finalVideoFile.Close()